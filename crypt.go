@@ -1,18 +1,31 @@
 package matasano
 
 import (
-	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"io"
 )
 
-func DecryptAESECB(cipher []byte, key []byte) []byte {
-	aes, err := aes.NewCipher(key)
-	CheckErr(err)
+func newAESCipher(key []byte) (cipher.Block, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, wrapErr(ErrInvalidKeyLength, err)
+	}
+	return block, nil
+}
 
-	dst := make([]byte, len(cipher))
-	for i := 0; i < len(dst); i += aes.BlockSize() {
-		aes.Decrypt(dst[i:], cipher[i:])
+func TryDecryptAESECB(cipher []byte, key []byte) ([]byte, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
 	}
+	return CryptBlocks(NewECBDecrypter(aes), cipher)
+}
+
+func DecryptAESECB(cipher []byte, key []byte) []byte {
+	dst, err := TryDecryptAESECB(cipher, key)
+	CheckErr(err)
 	return dst
 }
 
@@ -21,69 +34,206 @@ func Pkcs7Padding(src []byte, blocksize int) []byte {
 	dst := make([]byte, len(src)+more)
 	copy(dst, src)
 	for i := len(src); i < len(dst); i++ {
-		dst[i] = 4
+		dst[i] = byte(more)
 	}
 	return dst
 }
 
-func StripPadding(src []byte) []byte {
-	return bytes.TrimRight(src, "\x04")
+// PaddingError is returned when unpadding finds bytes that could not have
+// been produced by the padding scheme.
+type PaddingError struct {
+	Reason string
 }
 
-func EncryptAESECB(plaintext []byte, key []byte) []byte {
-	aes, err := aes.NewCipher(key)
+func (e *PaddingError) Error() string {
+	return "matasano: invalid padding: " + e.Reason
+}
+
+func Pkcs7Unpad(src []byte, blocksize int) ([]byte, error) {
+	if len(src) == 0 || len(src)%blocksize != 0 {
+		return nil, &PaddingError{"length is not a positive multiple of the block size"}
+	}
+	more := int(src[len(src)-1])
+	if more == 0 || more > blocksize {
+		return nil, &PaddingError{fmt.Sprintf("pad length %d out of range", more)}
+	}
+	for _, b := range src[len(src)-more:] {
+		if int(b) != more {
+			return nil, &PaddingError{fmt.Sprintf("pad byte %#02x does not match pad length %d", b, more)}
+		}
+	}
+	return src[:len(src)-more], nil
+}
+
+func StripPadding(src []byte, blocksize int) []byte {
+	dst, err := Pkcs7Unpad(src, blocksize)
 	CheckErr(err)
+	return dst
+}
 
-	dst := make([]byte, len(plaintext))
-	for i := 0; i < len(dst); i += aes.BlockSize() {
-		aes.Encrypt(dst[i:], plaintext[i:])
+func TryEncryptAESECB(plaintext []byte, key []byte) ([]byte, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
 	}
+	return CryptBlocks(NewECBEncrypter(aes), plaintext)
+}
+
+func EncryptAESECB(plaintext []byte, key []byte) []byte {
+	dst, err := TryEncryptAESECB(plaintext, key)
+	CheckErr(err)
 	return dst
 }
 
+func TryDecryptAESCBC(cipher []byte, key []byte, iv []byte) ([]byte, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := NewCBCDecrypter(aes, iv)
+	if err != nil {
+		return nil, err
+	}
+	return CryptBlocks(mode, cipher)
+}
+
 func DecryptAESCBC(cipher []byte, key []byte, iv []byte) []byte {
-	aes, err := aes.NewCipher(key)
+	dst, err := TryDecryptAESCBC(cipher, key, iv)
 	CheckErr(err)
+	return dst
+}
 
-	if len(iv) != aes.BlockSize() {
-		panic("aes.BlockSize != len(iv)")
+func TryEncryptAESCBC(plaintext []byte, key []byte, iv []byte) ([]byte, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
 	}
+	mode, err := NewCBCEncrypter(aes, iv)
+	if err != nil {
+		return nil, err
+	}
+	return CryptBlocks(mode, plaintext)
+}
+
+func EncryptAESCBC(plaintext []byte, key []byte, iv []byte) []byte {
+	dst, err := TryEncryptAESCBC(plaintext, key, iv)
+	CheckErr(err)
+	return dst
+}
+
+func TryEncryptAESECBPadded(plaintext []byte, key []byte, padding Padding) ([]byte, error) {
+	return TryEncryptAESECB(padding.Pad(plaintext, aes.BlockSize), key)
+}
+
+func EncryptAESECBPadded(plaintext []byte, key []byte, padding Padding) []byte {
+	dst, err := TryEncryptAESECBPadded(plaintext, key, padding)
+	CheckErr(err)
+	return dst
+}
 
-	dst := make([]byte, len(cipher))
-	for i := 0; i < len(dst); i += aes.BlockSize() {
-		til := i + aes.BlockSize()
-		aes.Decrypt(dst[i:], cipher[i:])
-		copy(dst[i:til], Xor(dst[i:til], iv))
-		iv = cipher[i:]
+func TryDecryptAESECBPadded(cipher []byte, key []byte, padding Padding) ([]byte, error) {
+	dst, err := TryDecryptAESECB(cipher, key)
+	if err != nil {
+		return nil, err
 	}
+	return padding.Unpad(dst, aes.BlockSize)
+}
+
+func DecryptAESECBPadded(cipher []byte, key []byte, padding Padding) []byte {
+	dst, err := TryDecryptAESECBPadded(cipher, key, padding)
+	CheckErr(err)
 	return dst
 }
 
-func EncryptAESCBC(plaintext []byte, key []byte, iv []byte) []byte {
-	aes, err := aes.NewCipher(key)
+func TryEncryptAESCBCPadded(plaintext []byte, key []byte, iv []byte, padding Padding) ([]byte, error) {
+	return TryEncryptAESCBC(padding.Pad(plaintext, aes.BlockSize), key, iv)
+}
+
+func EncryptAESCBCPadded(plaintext []byte, key []byte, iv []byte, padding Padding) []byte {
+	dst, err := TryEncryptAESCBCPadded(plaintext, key, iv, padding)
 	CheckErr(err)
+	return dst
+}
 
-	if len(iv) != aes.BlockSize() {
-		panic("aes.BlockSize != len(iv)")
+func TryDecryptAESCBCPadded(cipher []byte, key []byte, iv []byte, padding Padding) ([]byte, error) {
+	dst, err := TryDecryptAESCBC(cipher, key, iv)
+	if err != nil {
+		return nil, err
 	}
+	return padding.Unpad(dst, aes.BlockSize)
+}
+
+func DecryptAESCBCPadded(cipher []byte, key []byte, iv []byte, padding Padding) []byte {
+	dst, err := TryDecryptAESCBCPadded(cipher, key, iv, padding)
+	CheckErr(err)
+	return dst
+}
 
+// TryEncryptAESCTR is its own inverse; TryDecryptAESCTR is provided for
+// symmetry.
+func TryEncryptAESCTR(plaintext []byte, key []byte, nonce []byte, layout CTRLayout) ([]byte, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := NewCTRWithLayout(aes, nonce, layout)
+	if err != nil {
+		return nil, err
+	}
 	dst := make([]byte, len(plaintext))
-	for i := 0; i < len(dst); i += aes.BlockSize() {
-		til := i + aes.BlockSize()
-		part := Xor(iv, plaintext[i:til])
-		aes.Encrypt(dst[i:], part)
-		iv = dst[i:til]
+	stream.XORKeyStream(dst, plaintext)
+	return dst, nil
+}
+
+func EncryptAESCTR(plaintext []byte, key []byte, nonce []byte, layout CTRLayout) []byte {
+	dst, err := TryEncryptAESCTR(plaintext, key, nonce, layout)
+	CheckErr(err)
+	return dst
+}
+
+func TryDecryptAESCTR(cipher []byte, key []byte, nonce []byte, layout CTRLayout) ([]byte, error) {
+	return TryEncryptAESCTR(cipher, key, nonce, layout)
+}
+
+func DecryptAESCTR(cipher []byte, key []byte, nonce []byte, layout CTRLayout) []byte {
+	dst, err := TryDecryptAESCTR(cipher, key, nonce, layout)
+	CheckErr(err)
+	return dst
+}
+
+// TryEditAESCTR re-encrypts ciphertext from offset onwards with newtext,
+// seeking the keystream straight to offset rather than regenerating it from
+// the start. The edit is made in place when it fits; a ciphertext that must
+// grow is copied.
+func TryEditAESCTR(ciphertext []byte, key []byte, nonce []byte, layout CTRLayout, offset int, newtext []byte) ([]byte, error) {
+	if offset < 0 || offset > len(ciphertext) {
+		return nil, ErrInvalidOffset
 	}
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := NewSeekableCTR(aes, nonce, layout)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stream.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if end := offset + len(newtext); end > len(ciphertext) {
+		ciphertext = append(ciphertext[:len(ciphertext):len(ciphertext)], make([]byte, end-len(ciphertext))...)
+	}
+	stream.XORKeyStream(ciphertext[offset:offset+len(newtext)], newtext)
+	return ciphertext, nil
+}
+
+func EditAESCTR(ciphertext []byte, key []byte, nonce []byte, layout CTRLayout, offset int, newtext []byte) []byte {
+	dst, err := TryEditAESCTR(ciphertext, key, nonce, layout, offset, newtext)
+	CheckErr(err)
 	return dst
 }
 
 func CipherIsECB(cipher []byte, keysize int) bool {
-	for a := 0; a < len(cipher); a += keysize {
-		for b := 0; b < len(cipher); b += keysize {
-			if a != b && bytes.Equal(cipher[a:a+keysize], cipher[b:b+keysize]) {
-				return true
-			}
-		}
-	}
-	return false
+	return DetectECB(cipher, keysize).IsECB()
 }
@@ -2,30 +2,56 @@ package matasano
 
 import (
 	"bytes"
-	"io/ioutil"
+	"errors"
 	"reflect"
 	"testing"
 )
 
 func TestPkcs7Padding(t *testing.T) {
-	src := []byte("YELLOW SUBMARINE")
-	expected := []byte("YELLOW SUBMARINE\x04\x04\x04\x04")
-	got := Pkcs7Padding(src, 20)
+	cases := []struct {
+		src       []byte
+		blocksize int
+		expected  []byte
+	}{
+		{[]byte("YELLOW SUBMARINE"), 20, []byte("YELLOW SUBMARINE\x04\x04\x04\x04")},
+		{[]byte("YELLOW SUBMARINE"), 16, []byte("YELLOW SUBMARINE\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10\x10")},
+		{[]byte("ends in \x04"), 8, []byte("ends in \x04\x07\x07\x07\x07\x07\x07\x07")},
+	}
 
-	if !bytes.Equal(got, expected) {
-		t.Errorf("Pkcs7Padding(%q): got %q expected %q", src, got, expected)
+	for _, c := range cases {
+		got := Pkcs7Padding(c.src, c.blocksize)
+		if !bytes.Equal(got, c.expected) {
+			t.Errorf("Pkcs7Padding(%q, %d): got %q expected %q", c.src, c.blocksize, got, c.expected)
+		}
+
+		got = StripPadding(c.expected, c.blocksize)
+		if !bytes.Equal(got, c.src) {
+			t.Errorf("StripPadding(%q, %d): got %q expected %q", c.expected, c.blocksize, got, c.src)
+		}
 	}
+}
 
-	got = StripPadding(expected)
-	if !bytes.Equal(got, src) {
-		t.Errorf("StripPadding(%q): got %q expected %q", src, got, src)
+func TestPkcs7UnpadInvalid(t *testing.T) {
+	cases := [][]byte{
+		[]byte("ICE ICE BABY\x05\x05\x05\x05"),
+		[]byte("ICE ICE BABY\x01\x02\x03\x04"),
+		[]byte("ICE ICE BABY\x00\x00\x00\x00"),
+		[]byte("ICE ICE BABY\x04\x04\x04"),
+		[]byte(""),
+	}
+
+	for _, c := range cases {
+		_, err := Pkcs7Unpad(c, 16)
+		var perr *PaddingError
+		if !errors.As(err, &perr) {
+			t.Errorf("Pkcs7Unpad(%q): got err %v, expected a *PaddingError", c, err)
+		}
 	}
 }
 
 func TestDecryptAESCBC(t *testing.T) {
-	text, err := ioutil.ReadFile("data/set2_challenge10.txt")
+	cipher, err := LoadBase64File(Data, "data/set2_challenge10.txt")
 	CheckErr(err)
-	cipher := Base64(text).Decode()
 	key := []byte("YELLOW SUBMARINE")
 	iv := make([]byte, 16)
 	expected := []byte("I'm back and I'm ringin' the bell \nA rockin' on the mike while the fly girls yell \nIn ecstasy in the back of me \nWell that's my DJ Deshay cuttin' all them Z's \nHittin' hard and the girlies goin' crazy \nVanilla's on the mike, man I'm not lazy. \n\nI'm lettin' my drug kick in \nIt controls my mouth and I begin \nTo just let it flow, let my concepts go \nMy posse's to the side yellin', Go Vanilla Go! \n\nSmooth 'cause that's the way I will be \nAnd if you don't give a damn, then \nWhy you starin' at me \nSo get off 'cause I control the stage \nThere's no dissin' allowed \nI'm in my own phase \nThe girlies sa y they love me and that is ok \nAnd I can dance better than any kid n' play \n\nStage 2 -- Yea the one ya' wanna listen to \nIt's off my head so let the beat play through \nSo I can funk it up and make it sound good \n1-2-3 Yo -- Knock on some wood \nFor good luck, I like my rhymes atrocious \nSupercalafragilisticexpialidocious \nI'm an effect and that you can bet \nI can take a fly girl and make her wet. \n\nI'm like Samson -- Samson to Delilah \nThere's no denyin', You can try to hang \nBut you'll keep tryin' to get my style \nOver and over, practice makes perfect \nBut not if you're a loafer. \n\nYou'll get nowhere, no place, no time, no girls \nSoon -- Oh my God, homebody, you probably eat \nSpaghetti with a spoon! Come on and say it! \n\nVIP. Vanilla Ice yep, yep, I'm comin' hard like a rhino \nIntoxicating so you stagger like a wino \nSo punks stop trying and girl stop cryin' \nVanilla Ice is sellin' and you people are buyin' \n'Cause why the freaks are jockin' like Crazy Glue \nMovin' and groovin' trying to sing along \nAll through the ghetto groovin' this here song \nNow you're amazed by the VIP posse. \n\nSteppin' so hard like a German Nazi \nStartled by the bases hittin' ground \nThere's no trippin' on mine, I'm just gettin' down \nSparkamatic, I'm hangin' tight like a fanatic \nYou trapped me once and I thought that \nYou might have it \nSo step down and lend me your ear \n'89 in my time! You, '90 is my year. \n\nYou're weakenin' fast, YO! and I can tell it \nYour body's gettin' hot, so, so I can smell it \nSo don't be mad and don't be sad \n'Cause the lyrics belong to ICE, You can call me Dad \nYou're pitchin' a fit, so step back and endure \nLet the witch doctor, Ice, do the dance to cure \nSo come up close and don't be square \nYou wanna battle me -- Anytime, anywhere \n\nYou thought that I was weak, Boy, you're dead wrong \nSo come on, everybody and sing this song \n\nSay -- Play that funky music Say, go white boy, go white boy go \nplay that funky music Go white boy, go white boy, go \nLay down and boogie and play that funky music till you die. \n\nPlay that funky music Come on, Come on, let me hear \nPlay that funky music white boy you say it, say it \nPlay that funky music A little louder now \nPlay that funky music, white boy Come on, Come on, Come on \nPlay that funky music \n\x04\x04\x04\x04")
@@ -42,17 +68,15 @@ func TestDecryptAESCBC(t *testing.T) {
 }
 
 func TestCipherIsECB(t *testing.T) {
-	text, err := ioutil.ReadFile("data/set1_challenge8.txt")
+	lines, err := LoadHexLines(Data, "data/set1_challenge8.txt")
 	CheckErr(err)
-	lines := bytes.Split(text, []byte("\n"))
 	found := make([][]byte, 0)
 	expected := [][]byte{
 		Base64("2IBhl0CooZt4QKijHIEKPQhkmvcNwG9P1dLWnHRM0oPi3QUva2Qdv50RsDSFQrtXCGSa9w3Ab0/V0tacdEzSg5R1yd/bwdRll5SdnH6Cv1oIZJr3DcBvT9XS1px0TNKDl6k+q41q7NVmSJFUeJprAwhkmvcNwG9P1dLWnHRM0oPUAxgMmMj22x8qP5xAQN6wq1GymTPywSPFg4awb7oYag==").Decode(),
 	}
 
-	for _, line := range lines {
-		cipher := Hex(line).Decode()
-		if CipherIsECB(line, 16) {
+	for _, cipher := range lines {
+		if CipherIsECB(cipher, 16) {
 			found = append(found, cipher)
 		}
 	}
@@ -61,3 +85,93 @@ func TestCipherIsECB(t *testing.T) {
 		t.Errorf("TestCipherIsECB got %v, expected %v", found, expected)
 	}
 }
+
+func TestTryAESErrors(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	iv := make([]byte, 16)
+	block := make([]byte, 16)
+
+	cases := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{"short key", errOf(TryEncryptAESECB(block, []byte("short"))), ErrInvalidKeyLength},
+		{"ECB partial block", errOf(TryDecryptAESECB(block[:10], key)), ErrNotBlockMultiple},
+		{"CBC partial block", errOf(TryEncryptAESCBC(block[:10], key, iv)), ErrNotBlockMultiple},
+		{"CBC short IV", errOf(TryDecryptAESCBC(block, key, iv[:8])), ErrInvalidIVLength},
+	}
+
+	for _, c := range cases {
+		if !errors.Is(c.err, c.expected) {
+			t.Errorf("TestTryAESErrors(%s): got %v, expected %v", c.name, c.err, c.expected)
+		}
+	}
+}
+
+func errOf(_ []byte, err error) error {
+	return err
+}
+
+func TestAESCTRLayouts(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	cipher := Base64("L77na/nrFsKvynd6HzOoG7GHTLXsTVu9qvY/2syLXzhPweyyMTJULu/6/kXX0KSvoOLSFQ==").Decode()
+	expected := []byte("Yo, VIP Let's kick it Ice, Ice, baby Ice, Ice, baby ")
+
+	got, err := TryDecryptAESCTR(cipher, key, make([]byte, 8), CTRLittleEndian64)
+	if err != nil || !bytes.Equal(got, expected) {
+		t.Errorf("TryDecryptAESCTR little-endian 64: got %q, %v expected %q", got, err, expected)
+	}
+
+	// With a zero counter half and fewer than 2^64 blocks, the big-endian
+	// layouts agree with each other.
+	nonce := RandBytes(8)
+	a := EncryptAESCTR(expected, key, nonce, CTRBigEndian64)
+	b := EncryptAESCTR(expected, key, append(append([]byte{}, nonce...), make([]byte, 8)...), CTRBigEndian128)
+	if !bytes.Equal(a, b) {
+		t.Errorf("EncryptAESCTR big-endian 64 and 128 disagree: %x vs %x", a, b)
+	}
+
+	if _, err := TryEncryptAESCTR(expected, key, nonce, CTRBigEndian128); !errors.Is(err, ErrInvalidNonceLength) {
+		t.Errorf("TryEncryptAESCTR with 8 byte nonce for 128-bit counter: got %v, expected ErrInvalidNonceLength", err)
+	}
+	if _, err := TryEncryptAESCTR(expected, []byte("short"), nonce, CTRLittleEndian64); !errors.Is(err, ErrInvalidKeyLength) {
+		t.Errorf("TryEncryptAESCTR with short key: got %v, expected ErrInvalidKeyLength", err)
+	}
+}
+
+func TestEditAESCTR(t *testing.T) {
+	key, nonce := RandBytes(16), RandBytes(8)
+	plaintext := []byte("Yo, VIP Let's kick it Ice, Ice, baby Ice, Ice, baby")
+	cipher := EncryptAESCTR(plaintext, key, nonce, CTRLittleEndian64)
+
+	cases := []struct {
+		offset   int
+		newtext  string
+		expected string
+	}{
+		{0, "Hi", "Hi, VIP Let's kick it Ice, Ice, baby Ice, Ice, baby"},
+		{22, "ICE, ICE", "Yo, VIP Let's kick it ICE, ICE, baby Ice, Ice, baby"},
+		{47, "baby, too long", "Yo, VIP Let's kick it Ice, Ice, baby Ice, Ice, baby, too long"},
+	}
+	for _, c := range cases {
+		edited, err := TryEditAESCTR(append([]byte{}, cipher...), key, nonce, CTRLittleEndian64, c.offset, []byte(c.newtext))
+		if err != nil {
+			t.Fatalf("TryEditAESCTR(%d, %q): %v", c.offset, c.newtext, err)
+		}
+		got, err := TryDecryptAESCTR(edited, key, nonce, CTRLittleEndian64)
+		if err != nil || string(got) != c.expected {
+			t.Errorf("TryEditAESCTR(%d, %q): got %q expected %q", c.offset, c.newtext, got, c.expected)
+		}
+	}
+
+	inPlace := append([]byte{}, cipher...)
+	edited, err := TryEditAESCTR(inPlace, key, nonce, CTRLittleEndian64, 4, []byte("vip"))
+	if err != nil || &edited[0] != &inPlace[0] {
+		t.Errorf("TryEditAESCTR: expected an edit that fits to be made in place")
+	}
+
+	if _, err := TryEditAESCTR(cipher, key, nonce, CTRLittleEndian64, len(cipher)+1, []byte("x")); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("TryEditAESCTR past the end: got %v, expected ErrInvalidOffset", err)
+	}
+}
@@ -19,6 +19,13 @@ func CheckErr(err error) {
 	}
 }
 
+func TryXor(ab []byte, bb []byte) ([]byte, error) {
+	if len(bb) < len(ab) {
+		return nil, ErrLengthMismatch
+	}
+	return Xor(ab, bb), nil
+}
+
 func Xor(ab []byte, bb []byte) []byte {
 	c := make([]byte, len(ab))
 	for i := 0; i < len(c); i++ {
@@ -27,19 +34,33 @@ func Xor(ab []byte, bb []byte) []byte {
 	return c
 }
 
-func RandBytes(n int) []byte {
+func TryRandBytes(n int) ([]byte, error) {
 	b := make([]byte, n)
-	_, err := rand.Read(b)
+	if _, err := rand.Read(b); err != nil {
+		return nil, wrapErr(ErrRandomnessShortage, err)
+	}
+	return b, nil
+}
+
+func RandBytes(n int) []byte {
+	b, err := TryRandBytes(n)
 	CheckErr(err)
 	return b
 }
 
-func (h Hex) Decode() []byte {
+func (h Hex) TryDecode() ([]byte, error) {
 	dst := make([]byte, hex.DecodedLen(len(h)))
 	len, err := hex.Decode(dst, h)
-	CheckErr(err)
+	if err != nil {
+		return nil, wrapErr(ErrMalformedEncoding, err)
+	}
+	return dst[:len], nil
+}
 
-	return dst[:len]
+func (h Hex) Decode() []byte {
+	dst, err := h.TryDecode()
+	CheckErr(err)
+	return dst
 }
 
 func ToHex(src []byte) Hex {
@@ -54,9 +75,17 @@ func ToBase64(src []byte) Base64 {
 	return dst
 }
 
-func (b Base64) Decode() []byte {
+func (b Base64) TryDecode() ([]byte, error) {
 	dst := make([]byte, base64.StdEncoding.DecodedLen(len(b)))
 	len, err := base64.StdEncoding.Decode(dst, b)
+	if err != nil {
+		return nil, wrapErr(ErrMalformedEncoding, err)
+	}
+	return dst[:len], nil
+}
+
+func (b Base64) Decode() []byte {
+	dst, err := b.TryDecode()
 	CheckErr(err)
-	return dst[:len]
+	return dst
 }
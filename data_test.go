@@ -2,6 +2,7 @@ package matasano
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -38,3 +39,15 @@ func TestXor(t *testing.T) {
 		t.Errorf("Xor(%s, %s) == %s, want %s", orig, xor, got, expected)
 	}
 }
+
+func TestTryDecodeMalformed(t *testing.T) {
+	if _, err := Hex("zz").TryDecode(); !errors.Is(err, ErrMalformedEncoding) {
+		t.Errorf("Hex(%q).TryDecode(): got err %v, expected ErrMalformedEncoding", "zz", err)
+	}
+	if _, err := Base64("!!!!").TryDecode(); !errors.Is(err, ErrMalformedEncoding) {
+		t.Errorf("Base64(%q).TryDecode(): got err %v, expected ErrMalformedEncoding", "!!!!", err)
+	}
+	if _, err := TryXor([]byte("abc"), []byte("a")); !errors.Is(err, ErrLengthMismatch) {
+		t.Errorf("TryXor with short key: got err %v, expected ErrLengthMismatch", err)
+	}
+}
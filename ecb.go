@@ -0,0 +1,104 @@
+package matasano
+
+import "math"
+
+// ECBReport describes the repeated blocks found in a ciphertext.
+type ECBReport struct {
+	// Offset is where in the input block alignment starts.
+	Offset int
+	// Blocks is the number of whole blocks examined.
+	Blocks int
+	// Repeats counts blocks identical to an earlier block.
+	Repeats int
+	// Positions groups the byte offsets, into the input, of blocks that
+	// share the same contents, in order of first appearance.
+	Positions [][]int
+	// Confidence is the chance that random data of the same length would
+	// show fewer repeats than were found. It is 0 with no repeats. At
+	// ordinary block sizes random blocks almost never repeat, so any
+	// repeat gives close to 1; at small block sizes it takes more repeats
+	// than chance would explain.
+	Confidence float64
+}
+
+func (r ECBReport) IsECB() bool { return r.Repeats > 0 }
+
+// DetectECB looks for repeated blocks aligned to the start of cipher. A
+// trailing partial block is ignored.
+func DetectECB(cipher []byte, blocksize int) ECBReport {
+	return detectECBAt(cipher, blocksize, 0)
+}
+
+// FindECB runs DetectECB at every offset within a block, for ECB data
+// embedded at an unaligned position, and returns the report with the most
+// repeats.
+func FindECB(cipher []byte, blocksize int) ECBReport {
+	best := detectECBAt(cipher, blocksize, 0)
+	for offset := 1; offset < blocksize; offset++ {
+		if r := detectECBAt(cipher, blocksize, offset); r.Repeats > best.Repeats {
+			best = r
+		}
+	}
+	return best
+}
+
+func detectECBAt(cipher []byte, blocksize int, offset int) ECBReport {
+	report := ECBReport{Offset: offset}
+	if blocksize <= 0 || offset >= len(cipher) {
+		return report
+	}
+
+	groups := make(map[string]int)
+	for i := offset; i+blocksize <= len(cipher); i += blocksize {
+		report.Blocks++
+		block := string(cipher[i : i+blocksize])
+		if g, ok := groups[block]; ok {
+			report.Positions[g] = append(report.Positions[g], i)
+			report.Repeats++
+			continue
+		}
+		groups[block] = len(report.Positions)
+		report.Positions = append(report.Positions, []int{i})
+	}
+
+	repeated := report.Positions[:0]
+	for _, p := range report.Positions {
+		if len(p) > 1 {
+			repeated = append(repeated, p)
+		}
+	}
+	report.Positions = repeated
+
+	report.Confidence = poissonBelow(expectedRepeats(report.Blocks, blocksize), report.Repeats)
+	return report
+}
+
+// expectedRepeats is how many of n random blocks are expected to repeat an
+// earlier one: n less the expected number of distinct blocks. For large
+// blocks that difference is lost to rounding, so the expected number of
+// colliding pairs, which it approaches, is used instead.
+func expectedRepeats(n int, blocksize int) float64 {
+	values := math.Pow(2, float64(8*blocksize))
+	pairs := float64(n) * float64(n-1) / 2 / values
+	if pairs < 1e-6 {
+		return pairs
+	}
+	return float64(n) + values*math.Expm1(float64(n)*math.Log1p(-1/values))
+}
+
+// poissonBelow is the chance that a Poisson variable with mean lambda is
+// less than k.
+func poissonBelow(lambda float64, k int) float64 {
+	if k <= 0 {
+		return 0
+	}
+	if lambda <= 0 {
+		return 1
+	}
+	total := 0.0
+	for i := 0; i < k; i++ {
+		lgamma, _ := math.Lgamma(float64(i + 1))
+		total += math.Exp(float64(i)*math.Log(lambda) - lambda - lgamma)
+	}
+	return math.Min(1, total)
+}
@@ -0,0 +1,51 @@
+package matasano
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDetectECB(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	plaintext := bytes.Repeat([]byte("A"), 48)
+	cipher := append(EncryptAESECB(append(RandBytes(16), plaintext...), key), 1, 2, 3)
+
+	got := DetectECB(cipher, 16)
+	if got.Blocks != 4 || got.Repeats != 2 || !reflect.DeepEqual(got.Positions, [][]int{{16, 32, 48}}) || got.Confidence < 0.99 {
+		t.Errorf("DetectECB: got %+v", got)
+	}
+
+	got = DetectECB(RandBytes(160), 16)
+	if got.IsECB() || got.Confidence != 0 || len(got.Positions) != 0 {
+		t.Errorf("DetectECB on random data: got %+v", got)
+	}
+}
+
+func TestDetectECBConfidenceSmallBlocks(t *testing.T) {
+	// Two-byte blocks of random data repeat by chance: 4096 blocks are
+	// expected to show about 125 repeats.
+	random := DetectECB(RandBytes(8192), 2)
+	if random.Repeats == 0 || random.Confidence > 0.99999 {
+		t.Errorf("DetectECB on random two-byte blocks: got %d repeats, confidence %f", random.Repeats, random.Confidence)
+	}
+
+	repeated := DetectECB(bytes.Repeat(RandBytes(64), 128), 2)
+	if repeated.Confidence < 0.99999 {
+		t.Errorf("DetectECB on repeated two-byte blocks: got %d repeats, confidence %f", repeated.Repeats, repeated.Confidence)
+	}
+}
+
+func TestFindECBUnaligned(t *testing.T) {
+	cipher := EncryptAESECB([]byte("YELLOW SUBMARINEICE ICE BABY!!!!YELLOW SUBMARINE"), []byte("YELLOW SUBMARINE"))
+	embedded := append(append(RandBytes(37), cipher...), RandBytes(11)...)
+
+	if DetectECB(embedded, 16).IsECB() {
+		t.Errorf("DetectECB: expected no aligned repeats at offset 0")
+	}
+
+	got := FindECB(embedded, 16)
+	if got.Offset != 5 || got.Repeats != 1 || !reflect.DeepEqual(got.Positions, [][]int{{37, 69}}) {
+		t.Errorf("FindECB: got %+v, expected repeats at 37 and 69", got)
+	}
+}
@@ -0,0 +1,23 @@
+package matasano
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidKeyLength   = errors.New("matasano: invalid key length")
+	ErrInvalidIVLength    = errors.New("matasano: IV length does not match block size")
+	ErrInvalidNonceLength = errors.New("matasano: nonce length does not match CTR layout")
+	ErrNotBlockMultiple   = errors.New("matasano: input is not a multiple of the block size")
+	ErrLengthMismatch     = errors.New("matasano: inputs differ in length")
+	ErrMalformedEncoding  = errors.New("matasano: malformed encoding")
+	ErrRandomnessShortage = errors.New("matasano: could not read random bytes")
+	ErrInvalidOffset      = errors.New("matasano: invalid offset")
+)
+
+// wrapErr attaches the underlying cause to a sentinel so that callers can
+// match with errors.Is while still seeing what went wrong.
+func wrapErr(sentinel error, cause error) error {
+	return fmt.Errorf("%w: %v", sentinel, cause)
+}
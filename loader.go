@@ -0,0 +1,84 @@
+package matasano
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+// Data holds the challenge data files, so that tests and tools in any set
+// can load them without caring about the working directory.
+//
+//go:embed data/*.txt
+var Data embed.FS
+
+// LineError reports which line of a data file failed to decode.
+type LineError struct {
+	Name string
+	Line int
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.Name, e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error { return e.Err }
+
+// LoadBase64File decodes a file holding a single base64 value that may be
+// wrapped over several lines.
+func LoadBase64File(fsys fs.FS, name string) ([]byte, error) {
+	text, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := Base64(WhitespaceRegexp.ReplaceAll(text, nil)).TryDecode()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return dst, nil
+}
+
+// LoadHexLines decodes a file with one hex value per line. Blank lines are
+// skipped.
+func LoadHexLines(fsys fs.FS, name string) ([][]byte, error) {
+	return loadLines(fsys, name, func(line []byte) ([]byte, error) {
+		return Hex(line).TryDecode()
+	})
+}
+
+// LoadBase64Lines decodes a file with one base64 value per line. Blank lines
+// are skipped.
+func LoadBase64Lines(fsys fs.FS, name string) ([][]byte, error) {
+	return loadLines(fsys, name, func(line []byte) ([]byte, error) {
+		return Base64(line).TryDecode()
+	})
+}
+
+func loadLines(fsys fs.FS, name string, decode func([]byte) ([]byte, error)) ([][]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := make([][]byte, 0)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		dst, err := decode(line)
+		if err != nil {
+			return nil, &LineError{Name: name, Line: n, Err: err}
+		}
+		lines = append(lines, dst)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
@@ -0,0 +1,47 @@
+package matasano
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadDataFiles(t *testing.T) {
+	lines, err := LoadHexLines(Data, "data/set1_challenge4.txt")
+	if err != nil || len(lines) != 327 {
+		t.Errorf("LoadHexLines(set1_challenge4.txt): got %d lines, %v expected 327", len(lines), err)
+	}
+
+	text, err := LoadBase64File(Data, "data/set1_challenge6.txt")
+	if err != nil || len(text) != 2876 {
+		t.Errorf("LoadBase64File(set1_challenge6.txt): got %d bytes, %v expected 2876", len(text), err)
+	}
+}
+
+func TestLoadMalformedLines(t *testing.T) {
+	fsys := fstest.MapFS{
+		"hex.txt":    {Data: []byte("414243\n\n4g4243\n")},
+		"base64.txt": {Data: []byte("QUJD\nQUJ\n")},
+	}
+
+	cases := []struct {
+		name string
+		load func() ([][]byte, error)
+		line int
+	}{
+		{"hex.txt", func() ([][]byte, error) { return LoadHexLines(fsys, "hex.txt") }, 3},
+		{"base64.txt", func() ([][]byte, error) { return LoadBase64Lines(fsys, "base64.txt") }, 2},
+	}
+
+	for _, c := range cases {
+		_, err := c.load()
+		var lerr *LineError
+		if !errors.As(err, &lerr) || lerr.Line != c.line || !errors.Is(err, ErrMalformedEncoding) {
+			t.Errorf("loading %s: got %v, expected a malformed encoding error on line %d", c.name, err, c.line)
+		}
+	}
+
+	if _, err := LoadBase64File(fsys, "missing.txt"); err == nil {
+		t.Errorf("LoadBase64File(missing.txt): expected an error")
+	}
+}
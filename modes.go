@@ -0,0 +1,324 @@
+package matasano
+
+import (
+	"crypto/cipher"
+	"io"
+)
+
+// The modes below work with any cipher.Block. The BlockMode implementations
+// panic on partial blocks, as crypto/cipher's do; use CryptBlocks to get an
+// error instead.
+
+func CryptBlocks(mode cipher.BlockMode, src []byte) ([]byte, error) {
+	if err := checkBlocks(src, mode.BlockSize()); err != nil {
+		return nil, err
+	}
+	dst := make([]byte, len(src))
+	mode.CryptBlocks(dst, src)
+	return dst, nil
+}
+
+func checkBlocks(src []byte, blocksize int) error {
+	if len(src)%blocksize != 0 {
+		return ErrNotBlockMultiple
+	}
+	return nil
+}
+
+func checkCryptBlocks(dst, src []byte, blocksize int) {
+	if len(src)%blocksize != 0 {
+		panic("matasano: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("matasano: output smaller than input")
+	}
+}
+
+func copyIV(b cipher.Block, iv []byte) ([]byte, error) {
+	if len(iv) != b.BlockSize() {
+		return nil, ErrInvalidIVLength
+	}
+	return append([]byte{}, iv...), nil
+}
+
+type ecb struct {
+	b       cipher.Block
+	encrypt bool
+}
+
+func NewECBEncrypter(b cipher.Block) cipher.BlockMode { return &ecb{b, true} }
+func NewECBDecrypter(b cipher.Block) cipher.BlockMode { return &ecb{b, false} }
+
+func (e *ecb) BlockSize() int { return e.b.BlockSize() }
+
+func (e *ecb) CryptBlocks(dst, src []byte) {
+	bs := e.b.BlockSize()
+	checkCryptBlocks(dst, src, bs)
+	for i := 0; i < len(src); i += bs {
+		if e.encrypt {
+			e.b.Encrypt(dst[i:i+bs], src[i:i+bs])
+		} else {
+			e.b.Decrypt(dst[i:i+bs], src[i:i+bs])
+		}
+	}
+}
+
+type cbcEncrypter struct {
+	b  cipher.Block
+	iv []byte
+}
+
+func NewCBCEncrypter(b cipher.Block, iv []byte) (cipher.BlockMode, error) {
+	iv, err := copyIV(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	return &cbcEncrypter{b, iv}, nil
+}
+
+func (c *cbcEncrypter) BlockSize() int { return c.b.BlockSize() }
+
+func (c *cbcEncrypter) CryptBlocks(dst, src []byte) {
+	bs := c.b.BlockSize()
+	checkCryptBlocks(dst, src, bs)
+	for i := 0; i < len(src); i += bs {
+		c.b.Encrypt(dst[i:i+bs], Xor(src[i:i+bs], c.iv))
+		copy(c.iv, dst[i:i+bs])
+	}
+}
+
+type cbcDecrypter struct {
+	b  cipher.Block
+	iv []byte
+}
+
+func NewCBCDecrypter(b cipher.Block, iv []byte) (cipher.BlockMode, error) {
+	iv, err := copyIV(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	return &cbcDecrypter{b, iv}, nil
+}
+
+func (c *cbcDecrypter) BlockSize() int { return c.b.BlockSize() }
+
+func (c *cbcDecrypter) CryptBlocks(dst, src []byte) {
+	bs := c.b.BlockSize()
+	checkCryptBlocks(dst, src, bs)
+	block := make([]byte, bs)
+	for i := 0; i < len(src); i += bs {
+		// Keep the ciphertext block around in case dst and src overlap.
+		next := append([]byte{}, src[i:i+bs]...)
+		c.b.Decrypt(block, next)
+		copy(dst[i:i+bs], Xor(block, c.iv))
+		c.iv = next
+	}
+}
+
+// cfb is full-block cipher feedback: the feedback register is the previous
+// ciphertext block, as in crypto/cipher.
+type cfb struct {
+	b       cipher.Block
+	next    []byte
+	out     []byte
+	used    int
+	decrypt bool
+}
+
+func NewCFBEncrypter(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	return newCFB(b, iv, false)
+}
+
+func NewCFBDecrypter(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	return newCFB(b, iv, true)
+}
+
+func newCFB(b cipher.Block, iv []byte, decrypt bool) (cipher.Stream, error) {
+	iv, err := copyIV(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	bs := b.BlockSize()
+	return &cfb{b: b, next: iv, out: make([]byte, bs), used: bs, decrypt: decrypt}, nil
+}
+
+func (c *cfb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("matasano: output smaller than input")
+	}
+	for i, s := range src {
+		if c.used == len(c.out) {
+			c.b.Encrypt(c.out, c.next)
+			c.used = 0
+		}
+		if c.decrypt {
+			c.next[c.used] = s
+		}
+		dst[i] = s ^ c.out[c.used]
+		if !c.decrypt {
+			c.next[c.used] = dst[i]
+		}
+		c.used++
+	}
+}
+
+type ofb struct {
+	b    cipher.Block
+	out  []byte
+	used int
+}
+
+func NewOFB(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	iv, err := copyIV(b, iv)
+	if err != nil {
+		return nil, err
+	}
+	return &ofb{b: b, out: iv, used: len(iv)}, nil
+}
+
+func (o *ofb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("matasano: output smaller than input")
+	}
+	for i, s := range src {
+		if o.used == len(o.out) {
+			o.b.Encrypt(o.out, o.out)
+			o.used = 0
+		}
+		dst[i] = s ^ o.out[o.used]
+		o.used++
+	}
+}
+
+// CTRLayout describes how the nonce and block counter are packed into the
+// block that is encrypted to produce each block of keystream.
+type CTRLayout int
+
+const (
+	// CTRBigEndian128 treats the whole IV as one big-endian counter, as
+	// crypto/cipher and NIST SP 800-38A do.
+	CTRBigEndian128 CTRLayout = iota
+	// CTRLittleEndian64 is a half-block little-endian nonce followed by a
+	// half-block little-endian counter starting at zero. For AES that is
+	// the 64-bit/64-bit format used by Cryptopals set 3.
+	CTRLittleEndian64
+	// CTRBigEndian64 is CTRLittleEndian64 with both halves big-endian.
+	CTRBigEndian64
+)
+
+func (l CTRLayout) NonceSize(blocksize int) int {
+	if l == CTRBigEndian128 {
+		return blocksize
+	}
+	return blocksize / 2
+}
+
+type ctr struct {
+	b      cipher.Block
+	layout CTRLayout
+	nonce  []byte
+	index  uint64
+	input  []byte
+	out    []byte
+	used   int
+}
+
+func NewCTR(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	if len(iv) != b.BlockSize() {
+		return nil, ErrInvalidIVLength
+	}
+	return NewCTRWithLayout(b, iv, CTRBigEndian128)
+}
+
+func NewCTRWithLayout(b cipher.Block, nonce []byte, layout CTRLayout) (cipher.Stream, error) {
+	return NewSeekableCTR(b, nonce, layout)
+}
+
+// SeekableStream is a keystream that can be repositioned, so that any
+// part of a message can be processed without the bytes before it.
+type SeekableStream interface {
+	cipher.Stream
+	io.Seeker
+}
+
+func NewSeekableCTR(b cipher.Block, nonce []byte, layout CTRLayout) (SeekableStream, error) {
+	bs := b.BlockSize()
+	if len(nonce) != layout.NonceSize(bs) {
+		return nil, ErrInvalidNonceLength
+	}
+	return &ctr{
+		b:      b,
+		layout: layout,
+		nonce:  append([]byte{}, nonce...),
+		input:  make([]byte, bs),
+		out:    make([]byte, bs),
+		used:   bs,
+	}, nil
+}
+
+// counterBlock fills c.input with the counter block for keystream block
+// number index.
+func (c *ctr) counterBlock(index uint64) {
+	copy(c.input, c.nonce)
+	counter := c.input[len(c.nonce):]
+	switch c.layout {
+	case CTRBigEndian128:
+		carry := index
+		for j := len(c.input) - 1; j >= 0 && carry > 0; j-- {
+			sum := uint64(c.input[j]) + carry&0xff
+			c.input[j] = byte(sum)
+			carry = carry>>8 + sum>>8
+		}
+	case CTRLittleEndian64:
+		for j := range counter {
+			counter[j] = byte(index >> (8 * uint(j)))
+		}
+	case CTRBigEndian64:
+		for j := range counter {
+			counter[len(counter)-1-j] = byte(index >> (8 * uint(j)))
+		}
+	}
+}
+
+func (c *ctr) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("matasano: output smaller than input")
+	}
+	for i, s := range src {
+		if c.used == len(c.out) {
+			c.counterBlock(c.index)
+			c.b.Encrypt(c.out, c.input)
+			c.index++
+			c.used = 0
+		}
+		dst[i] = s ^ c.out[c.used]
+		c.used++
+	}
+}
+
+// Seek moves the keystream to byte offset. Seeking relative to the end is
+// not supported, as a keystream has no end.
+func (c *ctr) Seek(offset int64, whence int) (int64, error) {
+	bs := int64(len(c.out))
+	pos := int64(c.index)*bs - bs + int64(c.used)
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += pos
+	default:
+		return pos, ErrInvalidOffset
+	}
+	if offset < 0 {
+		return pos, ErrInvalidOffset
+	}
+
+	block, rem := offset/bs, offset%bs
+	if rem == 0 {
+		c.index, c.used = uint64(block), int(bs)
+	} else {
+		c.counterBlock(uint64(block))
+		c.b.Encrypt(c.out, c.input)
+		c.index, c.used = uint64(block)+1, int(rem)
+	}
+	return offset, nil
+}
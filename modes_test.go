@@ -0,0 +1,138 @@
+package matasano
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"errors"
+	"io"
+	"testing"
+)
+
+func modeBlocks() map[string]cipher.Block {
+	a, err := aes.NewCipher([]byte("YELLOW SUBMARINE"))
+	CheckErr(err)
+	d, err := des.NewCipher([]byte("8bytekey"))
+	CheckErr(err)
+	t, err := des.NewTripleDESCipher([]byte("twenty-four byte key 3DE"))
+	CheckErr(err)
+	return map[string]cipher.Block{"AES": a, "DES": d, "3DES": t}
+}
+
+func TestBlockModesMatchStdlib(t *testing.T) {
+	src := []byte("Play that funky music, white boy. Come on, come on, come on!!!!")
+
+	for name, b := range modeBlocks() {
+		bs := b.BlockSize()
+		iv := RandBytes(bs)
+		plaintext := src[:len(src)-len(src)%bs]
+
+		mine, err := NewCBCEncrypter(b, iv)
+		CheckErr(err)
+		got, err := CryptBlocks(mine, plaintext)
+		CheckErr(err)
+		expected := make([]byte, len(plaintext))
+		cipher.NewCBCEncrypter(b, iv).CryptBlocks(expected, plaintext)
+		if !bytes.Equal(got, expected) {
+			t.Errorf("%s CBC encrypt: got %x expected %x", name, got, expected)
+		}
+
+		mine, err = NewCBCDecrypter(b, iv)
+		CheckErr(err)
+		mine.CryptBlocks(got, got)
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("%s CBC in-place decrypt: got %q expected %q", name, got, plaintext)
+		}
+
+		got, err = CryptBlocks(NewECBDecrypter(b), mustCrypt(NewECBEncrypter(b), plaintext))
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("%s ECB round trip: got %q, %v expected %q", name, got, err, plaintext)
+		}
+
+		streams := []struct {
+			mode     string
+			mine     func(cipher.Block, []byte) (cipher.Stream, error)
+			stdlib   func(cipher.Block, []byte) cipher.Stream
+			decrypts func(cipher.Block, []byte) (cipher.Stream, error)
+		}{
+			{"CFB", NewCFBEncrypter, cipher.NewCFBEncrypter, NewCFBDecrypter},
+			{"OFB", NewOFB, cipher.NewOFB, NewOFB},
+			{"CTR", NewCTR, cipher.NewCTR, NewCTR},
+		}
+		for _, s := range streams {
+			stream, err := s.mine(b, iv)
+			CheckErr(err)
+			got := make([]byte, len(src))
+			// Feed in uneven pieces to exercise partial keystream blocks.
+			stream.XORKeyStream(got[:3], src[:3])
+			stream.XORKeyStream(got[3:], src[3:])
+
+			expected := make([]byte, len(src))
+			s.stdlib(b, iv).XORKeyStream(expected, src)
+			if !bytes.Equal(got, expected) {
+				t.Errorf("%s %s: got %x expected %x", name, s.mode, got, expected)
+			}
+
+			stream, err = s.decrypts(b, iv)
+			CheckErr(err)
+			stream.XORKeyStream(got, got)
+			if !bytes.Equal(got, src) {
+				t.Errorf("%s %s decrypt: got %q expected %q", name, s.mode, got, src)
+			}
+		}
+	}
+}
+
+func TestBlockModeErrors(t *testing.T) {
+	b := modeBlocks()["AES"]
+
+	if _, err := NewCBCEncrypter(b, make([]byte, 8)); !errors.Is(err, ErrInvalidIVLength) {
+		t.Errorf("NewCBCEncrypter with 8 byte IV: got %v, expected ErrInvalidIVLength", err)
+	}
+	if _, err := NewCTR(b, make([]byte, 20)); !errors.Is(err, ErrInvalidIVLength) {
+		t.Errorf("NewCTR with 20 byte IV: got %v, expected ErrInvalidIVLength", err)
+	}
+	if _, err := CryptBlocks(NewECBEncrypter(b), make([]byte, 17)); !errors.Is(err, ErrNotBlockMultiple) {
+		t.Errorf("CryptBlocks with 17 bytes: got %v, expected ErrNotBlockMultiple", err)
+	}
+}
+
+func TestSeekableCTR(t *testing.T) {
+	b := modeBlocks()["AES"]
+	nonce := RandBytes(8)
+	full := make([]byte, 100)
+	stream, err := NewSeekableCTR(b, nonce, CTRLittleEndian64)
+	CheckErr(err)
+	stream.XORKeyStream(full, full)
+
+	for _, offset := range []int64{0, 1, 15, 16, 17, 63, 99} {
+		stream, err := NewSeekableCTR(b, nonce, CTRLittleEndian64)
+		CheckErr(err)
+		pos, err := stream.Seek(offset, io.SeekStart)
+		if err != nil || pos != offset {
+			t.Fatalf("Seek(%d): got %d, %v", offset, pos, err)
+		}
+		got := make([]byte, 100-offset)
+		stream.XORKeyStream(got, got)
+		if !bytes.Equal(got, full[offset:]) {
+			t.Errorf("Seek(%d): keystream %x expected %x", offset, got, full[offset:])
+		}
+		if pos, _ := stream.Seek(-10, io.SeekCurrent); pos != 90 {
+			t.Errorf("Seek(-10, SeekCurrent) after reading to 100: got %d expected 90", pos)
+		}
+	}
+
+	if _, err := stream.Seek(-1, io.SeekStart); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("Seek(-1): got %v, expected ErrInvalidOffset", err)
+	}
+	if _, err := stream.Seek(0, io.SeekEnd); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("Seek(0, SeekEnd): got %v, expected ErrInvalidOffset", err)
+	}
+}
+
+func mustCrypt(mode cipher.BlockMode, src []byte) []byte {
+	dst, err := CryptBlocks(mode, src)
+	CheckErr(err)
+	return dst
+}
@@ -0,0 +1,159 @@
+package matasano
+
+import "fmt"
+
+// Padding is a block padding scheme. Pad always returns a fresh slice whose
+// length is a multiple of blocksize (except NoPadding, which leaves the
+// length alone); Unpad returns a *PaddingError when src could not have come
+// from Pad.
+type Padding interface {
+	Pad(src []byte, blocksize int) []byte
+	Unpad(src []byte, blocksize int) ([]byte, error)
+}
+
+var (
+	Pkcs7       Padding = pkcs7{}
+	AnsiX923    Padding = ansiX923{}
+	Iso7816     Padding = iso7816{}
+	Iso10126    Padding = iso10126{}
+	ZeroPadding Padding = zeroPadding{}
+	NoPadding   Padding = noPadding{}
+)
+
+type pkcs7 struct{}
+
+func (pkcs7) Pad(src []byte, blocksize int) []byte { return Pkcs7Padding(src, blocksize) }
+
+func (pkcs7) Unpad(src []byte, blocksize int) ([]byte, error) { return Pkcs7Unpad(src, blocksize) }
+
+func (pkcs7) String() string { return "PKCS#7" }
+
+// padTail returns src extended to the next block boundary, always adding at
+// least one byte, along with the number of bytes added.
+func padTail(src []byte, blocksize int) ([]byte, int) {
+	more := blocksize - (len(src) % blocksize)
+	dst := make([]byte, len(src)+more)
+	copy(dst, src)
+	return dst, more
+}
+
+// padLength reads and range-checks the trailing length byte used by
+// ANSI X.923 and ISO 10126.
+func padLength(src []byte, blocksize int) (int, error) {
+	if len(src) == 0 || len(src)%blocksize != 0 {
+		return 0, &PaddingError{"length is not a positive multiple of the block size"}
+	}
+	more := int(src[len(src)-1])
+	if more == 0 || more > blocksize {
+		return 0, &PaddingError{fmt.Sprintf("pad length %d out of range", more)}
+	}
+	return more, nil
+}
+
+type ansiX923 struct{}
+
+func (ansiX923) Pad(src []byte, blocksize int) []byte {
+	dst, more := padTail(src, blocksize)
+	dst[len(dst)-1] = byte(more)
+	return dst
+}
+
+func (ansiX923) Unpad(src []byte, blocksize int) ([]byte, error) {
+	more, err := padLength(src, blocksize)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range src[len(src)-more : len(src)-1] {
+		if b != 0 {
+			return nil, &PaddingError{fmt.Sprintf("fill byte %#02x is not zero", b)}
+		}
+	}
+	return src[:len(src)-more], nil
+}
+
+func (ansiX923) String() string { return "ANSI X.923" }
+
+type iso10126 struct{}
+
+func (iso10126) Pad(src []byte, blocksize int) []byte {
+	dst, more := padTail(src, blocksize)
+	copy(dst[len(src):], RandBytes(more-1))
+	dst[len(dst)-1] = byte(more)
+	return dst
+}
+
+func (iso10126) Unpad(src []byte, blocksize int) ([]byte, error) {
+	more, err := padLength(src, blocksize)
+	if err != nil {
+		return nil, err
+	}
+	return src[:len(src)-more], nil
+}
+
+func (iso10126) String() string { return "ISO 10126" }
+
+type iso7816 struct{}
+
+func (iso7816) Pad(src []byte, blocksize int) []byte {
+	dst, _ := padTail(src, blocksize)
+	dst[len(src)] = 0x80
+	return dst
+}
+
+func (iso7816) Unpad(src []byte, blocksize int) ([]byte, error) {
+	if len(src) == 0 || len(src)%blocksize != 0 {
+		return nil, &PaddingError{"length is not a positive multiple of the block size"}
+	}
+	i := len(src) - 1
+	for i >= 0 && src[i] == 0 {
+		i--
+	}
+	if i == -1 || src[i] != 0x80 {
+		return nil, &PaddingError{"missing 0x80 marker"}
+	}
+	if len(src)-i > blocksize {
+		return nil, &PaddingError{"padding is longer than a block"}
+	}
+	return src[:i], nil
+}
+
+func (iso7816) String() string { return "ISO/IEC 7816-4" }
+
+// zeroPadding fills with zeros only when src is not already aligned, so
+// Unpad cannot tell padding from plaintext that ends in zero bytes.
+type zeroPadding struct{}
+
+func (zeroPadding) Pad(src []byte, blocksize int) []byte {
+	more := (blocksize - (len(src) % blocksize)) % blocksize
+	dst := make([]byte, len(src)+more)
+	copy(dst, src)
+	return dst
+}
+
+func (zeroPadding) Unpad(src []byte, blocksize int) ([]byte, error) {
+	if len(src)%blocksize != 0 {
+		return nil, &PaddingError{"length is not a multiple of the block size"}
+	}
+	end := len(src)
+	for end > 0 && len(src)-end < blocksize && src[end-1] == 0 {
+		end--
+	}
+	return src[:end], nil
+}
+
+func (zeroPadding) String() string { return "zero" }
+
+type noPadding struct{}
+
+func (noPadding) Pad(src []byte, blocksize int) []byte {
+	return append([]byte{}, src...)
+}
+
+func (noPadding) Unpad(src []byte, blocksize int) ([]byte, error) {
+	if len(src)%blocksize != 0 {
+		return nil, &PaddingError{"length is not a multiple of the block size"}
+	}
+	return src, nil
+}
+
+func (noPadding) String() string { return "none" }
@@ -0,0 +1,103 @@
+package matasano
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var paddings = []Padding{Pkcs7, AnsiX923, Iso7816, Iso10126, ZeroPadding}
+
+func TestPaddingRoundTrip(t *testing.T) {
+	srcs := [][]byte{
+		[]byte(""),
+		[]byte("YELLOW"),
+		[]byte("YELLOW SUBMARINE"),
+		[]byte("YELLOW SUBMARINE!"),
+	}
+
+	for _, p := range paddings {
+		for _, src := range srcs {
+			padded := p.Pad(src, 16)
+			if len(padded)%16 != 0 {
+				t.Errorf("%v.Pad(%q): got len %d, expected a multiple of 16", p, src, len(padded))
+			}
+			got, err := p.Unpad(padded, 16)
+			if err != nil || !bytes.Equal(got, src) {
+				t.Errorf("%v.Unpad(%q): got %q, %v expected %q", p, padded, got, err, src)
+			}
+		}
+	}
+}
+
+func TestPaddingVectors(t *testing.T) {
+	src := []byte("YELLOW")
+	cases := []struct {
+		padding  Padding
+		expected []byte
+	}{
+		{Pkcs7, []byte("YELLOW\x02\x02")},
+		{AnsiX923, []byte("YELLOW\x00\x02")},
+		{Iso7816, []byte("YELLOW\x80\x00")},
+		{ZeroPadding, []byte("YELLOW\x00\x00")},
+		{NoPadding, []byte("YELLOW")},
+	}
+
+	for _, c := range cases {
+		got := c.padding.Pad(src, 8)
+		if !bytes.Equal(got, c.expected) {
+			t.Errorf("%v.Pad(%q, 8): got %q expected %q", c.padding, src, got, c.expected)
+		}
+	}
+
+	got := Iso10126.Pad(src, 8)
+	if len(got) != 8 || got[7] != 2 || !bytes.Equal(got[:6], src) {
+		t.Errorf("Iso10126.Pad(%q, 8): got %q", src, got)
+	}
+}
+
+func TestPaddingUnpadInvalid(t *testing.T) {
+	cases := []struct {
+		padding Padding
+		src     []byte
+	}{
+		{AnsiX923, []byte("YELLOW\x01\x02")},
+		{AnsiX923, []byte("YELLOW\x00\x09")},
+		{Iso7816, []byte("YELLOWS\x00")},
+		{Iso7816, []byte("YELLOW\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00")},
+		{Iso10126, []byte("YELLOW\x01\x00")},
+		{NoPadding, []byte("YELLOW")},
+	}
+
+	for _, c := range cases {
+		_, err := c.padding.Unpad(c.src, 8)
+		var perr *PaddingError
+		if !errors.As(err, &perr) {
+			t.Errorf("%v.Unpad(%q): got err %v, expected a *PaddingError", c.padding, c.src, err)
+		}
+	}
+}
+
+func TestAESPadded(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	iv := RandBytes(16)
+	src := []byte("Play that funky music, white boy")
+
+	for _, p := range paddings {
+		cipher := EncryptAESECBPadded(src, key, p)
+		got, err := TryDecryptAESECBPadded(cipher, key, p)
+		if err != nil || !bytes.Equal(got, src) {
+			t.Errorf("AES-ECB with %v padding: got %q, %v expected %q", p, got, err, src)
+		}
+
+		cipher = EncryptAESCBCPadded(src, key, iv, p)
+		got, err = TryDecryptAESCBCPadded(cipher, key, iv, p)
+		if err != nil || !bytes.Equal(got, src) {
+			t.Errorf("AES-CBC with %v padding: got %q, %v expected %q", p, got, err, src)
+		}
+	}
+
+	if _, err := TryEncryptAESECBPadded([]byte("short"), key, NoPadding); !errors.Is(err, ErrNotBlockMultiple) {
+		t.Errorf("TryEncryptAESECBPadded with NoPadding: got err %v, expected ErrNotBlockMultiple", err)
+	}
+}
@@ -0,0 +1,198 @@
+package set1
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"sync"
+
+	"github.com/mipearson/matasano"
+)
+
+// charModelOrder is the n-gram order of the character model: each letter
+// is predicted from up to five letters before it.
+const charModelOrder = 6
+
+// boundary is the model's symbol for the end of a word; 0-25 are letters.
+const boundary = 26
+
+// ngramContext holds up to charModelOrder-1 symbols. Contexts never reach
+// back past the start of the current word, so a context starting with
+// boundary means "the word so far is exactly this".
+type ngramContext struct {
+	n int8
+	s [charModelOrder - 1]byte
+}
+
+func (c ngramContext) push(sym byte) ngramContext {
+	if int(c.n) < len(c.s) {
+		c.s[c.n] = sym
+		c.n++
+		return c
+	}
+	copy(c.s[:], c.s[1:])
+	c.s[len(c.s)-1] = sym
+	return c
+}
+
+// shorter drops the oldest symbol, for backing off to a lower order.
+func (c ngramContext) shorter() ngramContext {
+	copy(c.s[:], c.s[1:c.n])
+	c.n--
+	c.s[c.n] = 0
+	return c
+}
+
+var wordStart = ngramContext{}.push(boundary)
+
+// charModel is an interpolated character n-gram model (Witten-Bell
+// smoothing) trained on data/english_words.txt, a list of common English
+// words weighted by rank. Because contexts stop at word boundaries it
+// models spelling and where words end rather than which words follow
+// which, and it backs off to shorter contexts for words it has not seen.
+type charModel struct {
+	logProbs map[ngramContext]*[27]float64
+	// words maps wordHash of each trained word to its probability.
+	words map[uint64]float64
+}
+
+var (
+	englishModel     *charModel
+	englishModelOnce sync.Once
+)
+
+func loadEnglishModel() *charModel {
+	englishModelOnce.Do(func() {
+		words, err := matasano.Data.ReadFile("data/english_words.txt")
+		matasano.CheckErr(err)
+		englishModel = trainCharModel(words)
+	})
+	return englishModel
+}
+
+func trainCharModel(words []byte) *charModel {
+	counts := map[ngramContext]*[27]float64{}
+	m := &charModel{logProbs: map[ngramContext]*[27]float64{}, words: map[uint64]float64{}}
+	total := 0.0
+	addWord := func(word []byte, weight float64) {
+		addWordCounts(counts, word, weight)
+		m.words[wordHash(word)] += weight
+		total += weight
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(words))
+	for rank := 0; scanner.Scan(); rank++ {
+		word := bytes.ToLower(bytes.TrimSpace(scanner.Bytes()))
+		if len(word) == 0 {
+			continue
+		}
+		// Zipf's law, scaled so that the rarest word still counts as
+		// about one observation.
+		weight := 1000 / float64(rank+10)
+		addWord(word, weight)
+		for _, suffix := range inflections {
+			addWord(append(append([]byte{}, word...), suffix.ending...), weight*suffix.weight)
+		}
+	}
+	for w := range m.words {
+		m.words[w] /= total
+	}
+
+	probs := map[ngramContext]*[27]float64{}
+	var smooth func(ctx ngramContext) *[27]float64
+	smooth = func(ctx ngramContext) *[27]float64 {
+		if p, ok := probs[ctx]; ok {
+			return p
+		}
+		lower := &[27]float64{}
+		if ctx.n == 0 {
+			for i := range lower {
+				lower[i] = 1.0 / 27
+			}
+		} else {
+			lower = smooth(ctx.shorter())
+		}
+		p := &[27]float64{}
+		c := counts[ctx]
+		total, types := 0.0, 0.0
+		for _, n := range c {
+			total += n
+			if n > 0 {
+				types++
+			}
+		}
+		for i := range p {
+			p[i] = (c[i] + types*lower[i]) / (total + types)
+		}
+		probs[ctx] = p
+		return p
+	}
+	for ctx := range counts {
+		p := smooth(ctx)
+		logs := &[27]float64{}
+		for i := range p {
+			logs[i] = math.Log(p[i])
+		}
+		m.logProbs[ctx] = logs
+	}
+	return m
+}
+
+// inflections are regular endings added to every word in the list, so
+// that plurals and verb forms are not treated as unseen words. They are
+// not always right ("runed"), but they are cheap and mostly help.
+var inflections = []struct {
+	ending string
+	weight float64
+}{
+	{"s", 0.3}, {"ed", 0.1}, {"ing", 0.1}, {"ly", 0.05}, {"er", 0.05},
+}
+
+func addWordCounts(counts map[ngramContext]*[27]float64, word []byte, weight float64) {
+	ctx := wordStart
+	for _, b := range append(word, ' ') {
+		sym := byte(boundary)
+		if b != ' ' {
+			sym = b - 'a'
+		}
+		if sym > boundary {
+			continue
+		}
+		for c := ctx; ; c = c.shorter() {
+			if counts[c] == nil {
+				counts[c] = &[27]float64{}
+			}
+			counts[c][sym] += weight
+			if c.n == 0 {
+				break
+			}
+		}
+		ctx = ctx.push(sym)
+	}
+}
+
+const fnvOffset, fnvPrime = 14695981039346656037, 1099511628211
+
+// wordHash is FNV-1a over the lowercased word, built up a byte at a time by
+// hashWordByte so that BreakRunningKeyXOR need not keep whole words.
+func wordHash(word []byte) uint64 {
+	h := uint64(fnvOffset)
+	for _, b := range word {
+		h = hashWordByte(h, b)
+	}
+	return h
+}
+
+func hashWordByte(h uint64, b byte) uint64 {
+	return (h ^ uint64(lower(b))) * fnvPrime
+}
+
+// distribution returns the log-probabilities of each symbol following
+// ctx, backing off to the longest context seen in training.
+func (m *charModel) distribution(ctx ngramContext) *[27]float64 {
+	for {
+		if p, ok := m.logProbs[ctx]; ok {
+			return p
+		}
+		ctx = ctx.shorter()
+	}
+}
@@ -0,0 +1,37 @@
+package set1
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCharModelDistribution(t *testing.T) {
+	m := loadEnglishModel()
+	for _, ctx := range []ngramContext{{}, wordStart, wordStart.push('t' - 'a').push('h' - 'a'), wordStart.push('q' - 'a').push('x' - 'a')} {
+		total := 0.0
+		for _, lp := range m.distribution(ctx) {
+			total += math.Exp(lp)
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Errorf("TestCharModelDistribution(%v): probabilities sum to %f", ctx, total)
+		}
+	}
+
+	th := m.distribution(wordStart.push('t' - 'a').push('h' - 'a'))
+	if th['e'-'a'] <= th['x'-'a'] {
+		t.Errorf("TestCharModelDistribution: expected \"the\" to be likelier than \"thx\"")
+	}
+}
+
+func TestCharModelWords(t *testing.T) {
+	m := loadEnglishModel()
+	if m.words[wordHash([]byte("the"))] <= m.words[wordHash([]byte("house"))] {
+		t.Errorf("TestCharModelWords: expected \"the\" to be commoner than \"house\"")
+	}
+	if m.words[wordHash([]byte("houses"))] == 0 {
+		t.Errorf("TestCharModelWords: expected inflected forms to be in the word list")
+	}
+	if m.words[wordHash([]byte("xyzzy"))] != 0 {
+		t.Errorf("TestCharModelWords: expected \"xyzzy\" not to be in the word list")
+	}
+}
@@ -0,0 +1,147 @@
+package set1
+
+import (
+	"sort"
+
+	"github.com/mipearson/matasano"
+)
+
+// CribDragger helps recover the keystream shared by several ciphertexts
+// by hand: drag a guessed word across one ciphertext, see which offsets make
+// the others readable, and lock in the keystream bytes that look right.
+type CribDragger struct {
+	// Scorer rates the other ciphertexts' plaintext at each offset. It
+	// defaults to ChiSquared, which rejects unprintable fragments outright.
+	Scorer      Scorer
+	ciphertexts [][]byte
+	keystream   []byte
+	known       []bool
+}
+
+type CribMatch struct {
+	Offset int
+	// Keystream is the keystream the crib implies at Offset.
+	Keystream []byte
+	// Plaintexts holds each ciphertext decrypted with Keystream at Offset,
+	// cut short where a ciphertext ends.
+	Plaintexts [][]byte
+	// Score is the mean score of the plaintexts other than the crib's.
+	Score float64
+}
+
+type CribMatches []CribMatch
+
+func NewCribDragger(ciphertexts ...[]byte) *CribDragger {
+	maxLen := 0
+	for _, c := range ciphertexts {
+		if len(c) > maxLen {
+			maxLen = len(c)
+		}
+	}
+	return &CribDragger{
+		Scorer:      ChiSquared,
+		ciphertexts: ciphertexts,
+		keystream:   make([]byte, maxLen),
+		known:       make([]bool, maxLen),
+	}
+}
+
+// Drag assumes crib is part of the plaintext of ciphertexts[index] and
+// tries it at every offset. Offsets where no other ciphertext is long enough
+// to check the crib against, or where the others score 0, are left out.
+func (d *CribDragger) Drag(crib []byte, index int) CribMatches {
+	source := d.ciphertexts[index]
+	matches := CribMatches{}
+
+	for offset := 0; offset+len(crib) <= len(source); offset++ {
+		keystream := matasano.Xor(source[offset:offset+len(crib)], crib)
+		match := CribMatch{
+			Offset:     offset,
+			Keystream:  keystream,
+			Plaintexts: make([][]byte, len(d.ciphertexts)),
+		}
+
+		total, others := 0.0, 0
+		for i, c := range d.ciphertexts {
+			match.Plaintexts[i] = d.decryptAt(c, offset, keystream)
+			if i == index || len(match.Plaintexts[i]) == 0 {
+				continue
+			}
+			total += d.Scorer.Score(match.Plaintexts[i])
+			others++
+		}
+		if others == 0 || total == 0 {
+			continue
+		}
+		match.Score = total / float64(others)
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+func (d *CribDragger) decryptAt(cipher []byte, offset int, keystream []byte) []byte {
+	if offset >= len(cipher) {
+		return nil
+	}
+	end := offset + len(keystream)
+	if end > len(cipher) {
+		end = len(cipher)
+	}
+	return matasano.Xor(cipher[offset:end], keystream)
+}
+
+// Top returns up to count matches, best first, without reordering a.
+func (a CribMatches) Top(count int) CribMatches {
+	sorted := append(CribMatches{}, a...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Score > sorted[j].Score })
+	if count > len(sorted) {
+		count = len(sorted)
+	}
+	return sorted[:count]
+}
+
+// Lock records keystream bytes starting at offset as known. Bytes that fall
+// past the end of the longest ciphertext are ignored.
+func (d *CribDragger) Lock(offset int, keystream []byte) {
+	for i, k := range keystream {
+		if offset+i < len(d.keystream) {
+			d.keystream[offset+i] = k
+			d.known[offset+i] = true
+		}
+	}
+}
+
+// LockPlaintext locks the keystream implied by ciphertexts[index] having
+// plaintext at offset.
+func (d *CribDragger) LockPlaintext(index int, offset int, plaintext []byte) {
+	d.Lock(offset, d.decryptAt(d.ciphertexts[index], offset, plaintext))
+}
+
+func (d *CribDragger) Unlock(offset int, length int) {
+	for i := offset; i < offset+length && i < len(d.known); i++ {
+		d.keystream[i] = 0
+		d.known[i] = false
+	}
+}
+
+func (d *CribDragger) Keystream() (keystream []byte, known []bool) {
+	return append([]byte{}, d.keystream...), append([]bool{}, d.known...)
+}
+
+// Plaintexts decrypts every ciphertext with the locked keystream, writing
+// unknown in place of bytes whose keystream has not been locked.
+func (d *CribDragger) Plaintexts(unknown byte) [][]byte {
+	plaintexts := make([][]byte, len(d.ciphertexts))
+	for i, c := range d.ciphertexts {
+		p := make([]byte, len(c))
+		for j, b := range c {
+			if d.known[j] {
+				p[j] = b ^ d.keystream[j]
+			} else {
+				p[j] = unknown
+			}
+		}
+		plaintexts[i] = p
+	}
+	return plaintexts
+}
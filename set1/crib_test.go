@@ -0,0 +1,51 @@
+package set1
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mipearson/matasano"
+)
+
+func TestCribDragger(t *testing.T) {
+	plaintexts := [][]byte{
+		[]byte("Steppin' so hard like a German Nazi"),
+		[]byte("Startled by the bases hittin' ground"),
+		[]byte("There's no trippin' on mine"),
+		[]byte("Sparkamatic, I'm hangin' tight"),
+	}
+	key := matasano.RandBytes(16)
+	nonce := make([]byte, 8)
+	ciphertexts := make([][]byte, len(plaintexts))
+	for i, p := range plaintexts {
+		ciphertexts[i] = matasano.EncryptAESCTR(p, key, nonce, matasano.CTRLittleEndian64)
+	}
+
+	d := NewCribDragger(ciphertexts...)
+	crib := []byte(" the ")
+	found := false
+	for _, m := range d.Drag(crib, 1).Top(3) {
+		if m.Offset == 11 {
+			found = true
+			if !bytes.Equal(m.Plaintexts[0], plaintexts[0][11:16]) {
+				t.Errorf("TestCribDragger: at offset 11 got %q, expected %q", m.Plaintexts[0], plaintexts[0][11:16])
+			}
+		}
+	}
+	if !found {
+		t.Errorf("TestCribDragger: expected offset 11 among the best matches for %q, got %+v", crib, d.Drag(crib, 1).Top(3))
+	}
+
+	d.LockPlaintext(1, 11, crib)
+	d.LockPlaintext(0, 0, []byte("Steppin'"))
+	got := d.Plaintexts('_')
+	if expected := []byte("There's ___tripp___________"); !bytes.Equal(got[2], expected) {
+		t.Errorf("TestCribDragger: after locking got %q, expected %q", got[2], expected)
+	}
+
+	d.Unlock(0, 8)
+	_, known := d.Keystream()
+	if known[0] || !known[11] {
+		t.Errorf("TestCribDragger: Unlock(0, 8) left known = %v", known[:16])
+	}
+}
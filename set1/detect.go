@@ -0,0 +1,163 @@
+package set1
+
+import (
+	"container/heap"
+	"context"
+	"runtime"
+	"sort"
+)
+
+// Detection is one line's best guess at a single-byte XOR key.
+type Detection struct {
+	// Line counts the lines received, from zero.
+	Line      int
+	Key       byte
+	Score     float64
+	Plaintext []byte
+}
+
+// DetectOptions tunes DetectSingleByteXOR. Zero values fall back to one
+// worker per CPU, the single best result and DefaultScorer.
+type DetectOptions struct {
+	Workers int
+	TopK    int
+	Scorer  Scorer
+}
+
+func (o DetectOptions) withDefaults() DetectOptions {
+	if o.Workers <= 0 {
+		o.Workers = runtime.GOMAXPROCS(0)
+	}
+	if o.TopK <= 0 {
+		o.TopK = 1
+	}
+	if o.Scorer == nil {
+		o.Scorer = DefaultScorer
+	}
+	return o
+}
+
+type detectJob struct {
+	n    int
+	line []byte
+}
+
+// DetectSingleByteXOR fans the lines read from lines out to a pool of
+// workers, each trying all 256 single-byte keys on a line, and keeps the
+// TopK (line, key, score) results across every line. Once lines is closed
+// and drained the results are sent best first and the returned channel is
+// closed. If ctx is cancelled it stops reading lines, finishes the lines
+// already being scored, and sends the TopK found so far in the same way,
+// so a long run can be cut short without losing its work. The channel is
+// buffered to hold all TopK results, so a caller that stops reading after
+// cancelling does not leak goroutines.
+func DetectSingleByteXOR(ctx context.Context, lines <-chan []byte, opts DetectOptions) <-chan Detection {
+	opts = opts.withDefaults()
+	out := make(chan Detection, opts.TopK)
+	jobs := make(chan detectJob)
+	found := make(chan []Detection)
+
+	go func() {
+		defer close(jobs)
+		for n := 0; ; n++ {
+			select {
+			case <-ctx.Done():
+				return
+			case line, ok := <-lines:
+				if !ok {
+					return
+				}
+				select {
+				case jobs <- detectJob{n, line}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	done := make(chan struct{})
+	for w := 0; w < opts.Workers; w++ {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			var buf []byte
+			for job := range jobs {
+				if ctx.Err() != nil {
+					continue
+				}
+				var detections []Detection
+				detections, buf = detectLine(job, buf, opts)
+				found <- detections
+			}
+		}()
+	}
+	go func() {
+		for w := 0; w < opts.Workers; w++ {
+			<-done
+		}
+		close(found)
+	}()
+
+	go func() {
+		defer close(out)
+		best := &detectionHeap{}
+		for detections := range found {
+			for _, d := range detections {
+				best.offer(d, opts.TopK)
+			}
+		}
+		sorted := []Detection(*best)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Score > sorted[j].Score })
+		for _, d := range sorted {
+			out <- d
+		}
+	}()
+	return out
+}
+
+// detectLine scores every key for one line, reusing buf for the trial
+// plaintexts, and returns the line's best TopK keys.
+func detectLine(job detectJob, buf []byte, opts DetectOptions) ([]Detection, []byte) {
+	if cap(buf) < len(job.line) {
+		buf = make([]byte, len(job.line))
+	}
+	buf = buf[:len(job.line)]
+
+	best := &detectionHeap{}
+	for k := 0; k < 256; k++ {
+		score := opts.Scorer.Score(singleByteXOR(buf, job.line, byte(k)))
+		if score > 0 && (best.Len() < opts.TopK || score > (*best)[0].Score) {
+			best.offer(Detection{
+				Line:      job.n,
+				Key:       byte(k),
+				Score:     score,
+				Plaintext: append([]byte{}, buf...),
+			}, opts.TopK)
+		}
+	}
+	return *best, buf
+}
+
+// detectionHeap is a min-heap on Score, so the weakest of the kept
+// detections is the one to drop.
+type detectionHeap []Detection
+
+func (h detectionHeap) Len() int            { return len(h) }
+func (h detectionHeap) Less(i, j int) bool  { return h[i].Score < h[j].Score }
+func (h detectionHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *detectionHeap) Push(x interface{}) { *h = append(*h, x.(Detection)) }
+func (h *detectionHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func (h *detectionHeap) offer(d Detection, k int) {
+	if h.Len() < k {
+		heap.Push(h, d)
+	} else if d.Score > (*h)[0].Score {
+		(*h)[0] = d
+		heap.Fix(h, 0)
+	}
+}
@@ -0,0 +1,105 @@
+package set1
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mipearson/matasano"
+)
+
+func TestDetectSingleByteXOR(t *testing.T) {
+	ciphertexts, err := matasano.LoadHexLines(matasano.Data, "data/set1_challenge4.txt")
+	matasano.CheckErr(err)
+
+	lines := make(chan []byte)
+	go func() {
+		for _, c := range ciphertexts {
+			lines <- c
+		}
+		close(lines)
+	}()
+
+	got := []Detection{}
+	for d := range DetectSingleByteXOR(context.Background(), lines, DetectOptions{Workers: 4, TopK: 3, Scorer: ChiSquared}) {
+		got = append(got, d)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("DetectSingleByteXOR: got %d detections, expected 3", len(got))
+	}
+	best := got[0]
+	if best.Key != '5' || !bytes.Equal(best.Plaintext, []byte("Now that the party is jumping\n")) || !bytes.Equal(ciphertexts[best.Line], singleByteXOR(make([]byte, len(best.Plaintext)), best.Plaintext, '5')) {
+		t.Errorf("DetectSingleByteXOR: got best %+v", best)
+	}
+	if got[1].Score > got[0].Score || got[2].Score > got[1].Score {
+		t.Errorf("DetectSingleByteXOR: results not best first: %+v", got)
+	}
+}
+
+func TestDetectSingleByteXORCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines := make(chan []byte)
+	go func() {
+		for {
+			select {
+			case lines <- []byte("never ending"):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Cancel part way through the sixth line scored, so at least five
+	// whole lines have been scored and must be reported.
+	calls := int64(0)
+	scorer := ScorerFunc(func(b []byte) float64 {
+		if atomic.AddInt64(&calls, 1) == 5*256+100 {
+			cancel()
+		}
+		return ChiSquared.Score(b)
+	})
+
+	got := []Detection{}
+	for d := range DetectSingleByteXOR(ctx, lines, DetectOptions{Workers: 1, TopK: 3, Scorer: scorer}) {
+		got = append(got, d)
+	}
+	if len(got) != 3 {
+		t.Fatalf("DetectSingleByteXOR after cancel: got %d detections, expected 3", len(got))
+	}
+	for _, d := range got {
+		if !bytes.Equal(singleByteXOR(make([]byte, len(d.Plaintext)), d.Plaintext, d.Key), []byte("never ending")) {
+			t.Errorf("DetectSingleByteXOR after cancel: got %+v", d)
+		}
+	}
+	if got[1].Score > got[0].Score || got[2].Score > got[1].Score {
+		t.Errorf("DetectSingleByteXOR after cancel: results not best first: %+v", got)
+	}
+}
+
+func TestDetectSingleByteXORCancelWithoutReading(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan []byte, 10)
+	for i := 0; i < 10; i++ {
+		lines <- []byte("abandoned")
+	}
+	results := DetectSingleByteXOR(ctx, lines, DetectOptions{TopK: 2})
+	cancel()
+
+	// Results are buffered, so the pipeline finishes and closes the
+	// channel even though nobody reads until afterwards.
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-results:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatalf("DetectSingleByteXOR: channel not closed after cancel")
+		}
+	}
+}
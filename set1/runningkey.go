@@ -0,0 +1,236 @@
+package set1
+
+import (
+	"math"
+	"sort"
+
+	"github.com/mipearson/matasano"
+)
+
+// runningKeyAlphabet is what BreakRunningKeyXOR assumes both texts are
+// made of.
+const runningKeyAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ .,'\n"
+
+// runningKeyBeamWidth is how many pairs of partial texts BreakRunningKeyXOR
+// keeps at each position.
+const runningKeyBeamWidth = 500
+
+// BreakRunningKeyXOR recovers two English texts that were XORed together.
+//
+// The other breakers in this package split the ciphertext into columns
+// that share a key byte and rank each column with a Scorer. A running key
+// never repeats, so there are no columns: every byte has its own unknown
+// key byte and is only pinned down by which letters are likely to follow
+// the ones before it, in both texts at once. The Scorers rate whole texts
+// and are too coarse for that; a search driven by their unigram and
+// n-gram tables gets about half the bytes right. So this is a beam search
+// over both texts under the word-level character model in charmodel.go.
+// It does well on ordinary prose and worse on names and rare words.
+//
+// The two texts are interchangeable, so the returned plaintext and key can
+// swap over part way through. It returns false if no pair of texts drawn
+// from runningKeyAlphabet fits the ciphertext.
+func BreakRunningKeyXOR(cipher []byte) (Candidate, bool) {
+	if len(cipher) == 0 {
+		return Candidate{}, false
+	}
+	model := loadEnglishModel()
+
+	type node struct {
+		p, k   textState
+		score  float64
+		parent int
+		char   byte
+	}
+	type stateKey struct{ p, k textKey }
+
+	start := textState{ctx: wordStart, sentence: true, word: fnvOffset}
+	beam := []node{{p: start, k: start, parent: -1}}
+	history := make([][]node, 0, len(cipher))
+	for _, c := range cipher {
+		next := make([]node, 0, len(beam)*8)
+		seen := map[stateKey]int{}
+		for i, n := range beam {
+			for j := 0; j < len(runningKeyAlphabet); j++ {
+				p := runningKeyAlphabet[j]
+				k := p ^ c
+				// When both texts are in the same state, (p, k) and
+				// (k, p) lead to mirror images; only follow one.
+				if n.p.key() == n.k.key() && p > k {
+					continue
+				}
+				kp, ok := n.k.step(model, k)
+				if !ok {
+					continue
+				}
+				pp, _ := n.p.step(model, p)
+				score := n.score + pp.logProb + kp.logProb
+				pp.logProb, kp.logProb = 0, 0
+
+				key := stateKey{pp.key(), kp.key()}
+				if at, ok := seen[key]; ok {
+					if score > next[at].score {
+						next[at] = node{pp, kp, score, i, p}
+					}
+					continue
+				}
+				seen[key] = len(next)
+				next = append(next, node{pp, kp, score, i, p})
+			}
+		}
+		if len(next) == 0 {
+			return Candidate{}, false
+		}
+		sort.Slice(next, func(a, b int) bool { return next[a].score > next[b].score })
+		if len(next) > runningKeyBeamWidth {
+			next = next[:runningKeyBeamWidth]
+		}
+		history = append(history, beam)
+		beam = next
+	}
+
+	plaintext := make([]byte, len(cipher))
+	at := 0
+	for i := len(cipher) - 1; i >= 0; i-- {
+		plaintext[i] = beam[at].char
+		at = beam[at].parent
+		beam = history[i]
+	}
+	return Candidate{
+		plaintext: plaintext,
+		key:       matasano.Xor(cipher, plaintext),
+		score:     Bigrams.Score(plaintext),
+	}, true
+}
+
+// textState is where one of BreakRunningKeyXOR's texts has got to: the
+// model context and hash of the current word, whether the last byte was
+// punctuation that should be followed by a space, and whether a sentence
+// is starting. logProb carries the cost of the last step out of step.
+type textState struct {
+	ctx      ngramContext
+	punct    bool
+	sentence bool
+	word     uint64
+	wordLog  float64
+	logProb  float64
+}
+
+// key is the part of t that decides how the text can continue.
+func (t textState) key() textKey {
+	return textKey{t.ctx, t.punct, t.sentence, t.word}
+}
+
+type textKey struct {
+	ctx      ngramContext
+	punct    bool
+	sentence bool
+	word     uint64
+}
+
+// The character model only knows lowercase words. These are rough rates
+// for English prose of what it leaves out: capitals, punctuation and line
+// breaks. Anything that breaks the rules of the alphabet's punctuation,
+// such as a space after a space, costs rateUnlikely.
+const (
+	rateCapitalWord     = 0.1
+	rateCapitalSentence = 0.7
+	rateCapitalInside   = 0.002
+	rateComma           = 0.06
+	rateFullStop        = 0.04
+	rateApostrophe      = 0.01
+	rateNewline         = 0.05
+	rateUnlikely        = 1e-4
+)
+
+// vocabularyWeight is how much of a finished word's probability comes from
+// the model's word list rather than from its spelling.
+const vocabularyWeight = 0.5
+
+// step extends the text with b, returning false if b is not in
+// runningKeyAlphabet.
+func (t textState) step(m *charModel, b byte) (textState, bool) {
+	atStart := t.ctx == wordStart
+	next := textState{ctx: t.ctx, sentence: t.sentence, word: t.word, wordLog: t.wordLog}
+	switch {
+	case b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z':
+		l := lower(b)
+		next.logProb = m.distribution(t.ctx)[l-'a'] + math.Log(capitalRate(t.sentence, atStart, l != b))
+		if t.punct {
+			next.logProb += math.Log(rateUnlikely)
+		}
+		next.ctx, next.sentence = t.ctx.push(l-'a'), false
+		next.word = hashWordByte(t.word, l)
+		next.wordLog += m.distribution(t.ctx)[l-'a']
+	case b == ' ' || b == '\n':
+		switch {
+		case t.punct:
+			next.logProb = math.Log(1 - rateNewline)
+		case atStart:
+			next.logProb = math.Log(rateUnlikely)
+		default:
+			next.logProb = t.finishWord(m)
+		}
+		if b == '\n' {
+			next.logProb += math.Log(rateNewline)
+		}
+		next.ctx, next.word, next.wordLog = wordStart, fnvOffset, 0
+	case b == '.' || b == ',':
+		if atStart || t.punct {
+			next.logProb = math.Log(rateUnlikely)
+		} else {
+			next.logProb = t.finishWord(m)
+		}
+		if b == '.' {
+			next.logProb += math.Log(rateFullStop)
+		} else {
+			next.logProb += math.Log(rateComma)
+		}
+		next.ctx, next.punct, next.sentence = wordStart, true, b == '.'
+		next.word, next.wordLog = fnvOffset, 0
+	case b == '\'':
+		next.logProb = math.Log(rateApostrophe)
+		if atStart || t.punct {
+			next.logProb += math.Log(rateUnlikely)
+		}
+	default:
+		return t, false
+	}
+	return next, true
+}
+
+// capitalRate is the chance of a letter being upper or lower case, given
+// whether it starts a sentence or a word.
+func capitalRate(sentence, atStart, upper bool) float64 {
+	p := rateCapitalInside
+	switch {
+	case sentence:
+		p = rateCapitalSentence
+	case atStart:
+		p = rateCapitalWord
+	}
+	if !upper {
+		if !sentence && !atStart {
+			return 1
+		}
+		return 1 - p
+	}
+	return p
+}
+
+// finishWord is the log-probability of the current word ending here. The
+// letters were scored by the character model alone; this swaps that
+// score for a mixture with the word's probability in the word list.
+func (t textState) finishWord(m *charModel) float64 {
+	end := m.distribution(t.ctx)[boundary]
+	spelled := t.wordLog + end
+	mixed := math.Log(vocabularyWeight*m.words[t.word] + (1-vocabularyWeight)*math.Exp(spelled))
+	return end + mixed - spelled
+}
+
+func lower(b byte) byte {
+	if b >= 'A' && b <= 'Z' {
+		return b + 'a' - 'A'
+	}
+	return b
+}
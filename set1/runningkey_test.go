@@ -0,0 +1,64 @@
+package set1
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBreakRunningKeyXOR(t *testing.T) {
+	// BreakRunningKeyXOR is deterministic, and these pairs, which were not
+	// used to tune it, come back with every byte right.
+	cases := []struct {
+		a, b []byte
+	}{
+		{
+			[]byte("It was the best of times, it was the worst of times, it was the age of wisdom"),
+			[]byte("When the day was over, the children went back to their homes to sleep."),
+		},
+		{
+			[]byte("The people of the world want peace, and they want it now more than ever."),
+			[]byte("When the day was over, the children went back to their homes to sleep."),
+		},
+		{
+			[]byte("Every family has a story, and every story has a place where it began."),
+			[]byte("When the day was over, the children went back to their homes to sleep."),
+		},
+		{
+			[]byte("She walked down to the river every morning to watch the boats go by."),
+			[]byte("He said that he would come back in the morning with some more food."),
+		},
+	}
+
+	for _, c := range cases {
+		n := len(c.a)
+		if len(c.b) < n {
+			n = len(c.b)
+		}
+		a, b := c.a[:n], c.b[:n]
+		cipher := RunningKeyXOR(a, b)
+		got, ok := BreakRunningKeyXOR(cipher)
+		if !ok {
+			t.Fatalf("BreakRunningKeyXOR(%q): found no solution", a)
+		}
+
+		// The texts are interchangeable: where the plaintext holds a byte
+		// of one, the key holds the matching byte of the other.
+		for i, p := range got.Plaintext() {
+			k := got.Key()[i]
+			if !(p == a[i] && k == b[i]) && !(p == b[i] && k == a[i]) {
+				t.Errorf("BreakRunningKeyXOR(%q): got %q, wrong from byte %d", a, got.Plaintext(), i)
+				break
+			}
+		}
+		if !bytes.Equal(RunningKeyXOR(got.Plaintext(), got.Key()), cipher) {
+			t.Errorf("BreakRunningKeyXOR: plaintext and key do not XOR back to the cipher")
+		}
+	}
+
+	if _, ok := BreakRunningKeyXOR([]byte{0xff, 0xff}); ok {
+		t.Errorf("BreakRunningKeyXOR: expected no solution outside the alphabet")
+	}
+	if _, ok := BreakRunningKeyXOR(nil); ok {
+		t.Errorf("BreakRunningKeyXOR: expected no solution for an empty cipher")
+	}
+}
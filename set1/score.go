@@ -0,0 +1,208 @@
+package set1
+
+import (
+	"bytes"
+	"math"
+	"unicode/utf8"
+)
+
+// Scorer rates how much a plaintext looks like English. Scores are never
+// negative and higher is better; a score of 0 rejects the plaintext.
+type Scorer interface {
+	Score(plaintext []byte) float64
+}
+
+type ScorerFunc func(plaintext []byte) float64
+
+func (f ScorerFunc) Score(plaintext []byte) float64 { return f(plaintext) }
+
+var (
+	// FrequencyRank is the original scorer: each letter scores its rank
+	// in Frequencies, and any byte from Disqualifiers rejects the text.
+	FrequencyRank Scorer = ScorerFunc(frequencyRankScore)
+	// ChiSquared compares byte counts with English unigram frequencies,
+	// space included. It is the default.
+	ChiSquared Scorer = ScorerFunc(chiSquaredScore)
+	// Bigrams and Trigrams score the mean log-likelihood of the letter
+	// n-grams inside words. They need contiguous text, so they are no use
+	// on the every-nth-byte columns of a repeating-key cipher, but they are
+	// good at ranking whole plaintexts.
+	Bigrams  Scorer = ngramScorer{2, bigramFrequencies, 1e-4}
+	Trigrams Scorer = ngramScorer{3, trigramFrequencies, 1e-5}
+	// Printable is the fraction of bytes that are printable ASCII.
+	Printable Scorer = ScorerFunc(printableScore)
+
+	DefaultScorer = ChiSquared
+)
+
+func frequencyRankScore(plaintext []byte) float64 {
+	if !utf8.Valid(plaintext) || bytes.IndexAny(plaintext, Disqualifiers) != -1 {
+		return 0
+	}
+	score := 0
+	asUpper := bytes.ToUpper(plaintext)
+	for _, b := range asUpper {
+		score += scoreOfByte(b)
+	}
+	return float64(score)
+}
+
+func scoreOfByte(src byte) int {
+	idx := bytes.IndexByte([]byte(Frequencies), src)
+	if idx == -1 {
+		return 0
+	} else {
+		return idx
+	}
+}
+
+func isPrintable(b byte) bool {
+	return (b >= 0x20 && b < 0x7f) || b == '\n' || b == '\r' || b == '\t'
+}
+
+func printableScore(plaintext []byte) float64 {
+	if len(plaintext) == 0 {
+		return 0
+	}
+	count := 0
+	for _, b := range plaintext {
+		if isPrintable(b) {
+			count++
+		}
+	}
+	return float64(count) / float64(len(plaintext))
+}
+
+// englishFrequencies are unigram frequencies of English text with space,
+// ignoring case and punctuation.
+var englishFrequencies = map[byte]float64{
+	' ': 0.1918182, 'e': 0.1041442, 't': 0.0729357, 'a': 0.0651738,
+	'o': 0.0596302, 'n': 0.0564513, 'i': 0.0558094, 's': 0.0515760,
+	'r': 0.0497563, 'h': 0.0492888, 'd': 0.0349835, 'l': 0.0331490,
+	'u': 0.0225134, 'c': 0.0217339, 'm': 0.0202124, 'f': 0.0197881,
+	'w': 0.0171272, 'g': 0.0158610, 'y': 0.0145984, 'p': 0.0137645,
+	'b': 0.0124248, 'v': 0.0082903, 'k': 0.0050529, 'x': 0.0013692,
+	'j': 0.0009033, 'q': 0.0008606, 'z': 0.0007836,
+}
+
+// otherFrequency is the share of the text expected to be digits,
+// punctuation and line breaks, which are counted together.
+const otherFrequency = 0.02
+
+func chiSquaredScore(plaintext []byte) float64 {
+	if len(plaintext) == 0 {
+		return 0
+	}
+	counts := make(map[byte]int)
+	other := 0
+	for _, b := range plaintext {
+		if !isPrintable(b) {
+			return 0
+		}
+		if b >= 'A' && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		if _, ok := englishFrequencies[b]; ok {
+			counts[b]++
+		} else {
+			other++
+		}
+	}
+
+	n := float64(len(plaintext))
+	chi := 0.0
+	for b, f := range englishFrequencies {
+		expected := n * f * (1 - otherFrequency)
+		diff := float64(counts[b]) - expected
+		chi += diff * diff / expected
+	}
+	expected := n * otherFrequency
+	diff := float64(other) - expected
+	chi += diff * diff / expected
+
+	return 1 / (1 + chi/n)
+}
+
+type ngramScorer struct {
+	n           int
+	frequencies map[string]float64
+	floor       float64
+}
+
+// Score is the geometric mean probability of the n-grams, so it does not
+// depend on the text's length. Unusual bytes count as extra terms at the
+// floor probability, and unprintable ones at the floor squared, so a few
+// bad bytes lower the score rather than reject the text.
+func (s ngramScorer) Score(plaintext []byte) float64 {
+	if len(plaintext) == 0 {
+		return 0
+	}
+	logp := 0.0
+	count := 0
+	for _, b := range plaintext {
+		switch {
+		case !isPrintable(b):
+			logp += 2 * math.Log(s.floor)
+			count++
+		case !isLetter(b) && bytes.IndexByte([]byte(commonPunctuation), b) == -1:
+			logp += math.Log(s.floor)
+			count++
+		}
+	}
+	for _, word := range bytes.FieldsFunc(bytes.ToLower(plaintext), func(r rune) bool { return r < 'a' || r > 'z' }) {
+		for i := 0; i+s.n <= len(word); i++ {
+			f, ok := s.frequencies[string(word[i:i+s.n])]
+			if !ok {
+				f = s.floor
+			}
+			logp += math.Log(f)
+			count++
+		}
+	}
+	if count == 0 {
+		return s.floor
+	}
+	return math.Exp(logp / float64(count))
+}
+
+const commonPunctuation = " \n\r\t.,;:'\"!?-()"
+
+func isLetter(b byte) bool {
+	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+var bigramFrequencies = map[string]float64{
+	"th": 0.0356, "he": 0.0307, "in": 0.0243, "er": 0.0205, "an": 0.0199,
+	"re": 0.0185, "on": 0.0176, "at": 0.0149, "en": 0.0145, "nd": 0.0135,
+	"ti": 0.0134, "es": 0.0134, "or": 0.0128, "te": 0.0120, "of": 0.0117,
+	"ed": 0.0117, "is": 0.0113, "it": 0.0112, "al": 0.0109, "ar": 0.0107,
+	"st": 0.0105, "to": 0.0104, "nt": 0.0104, "ng": 0.0095, "se": 0.0093,
+	"ha": 0.0093, "as": 0.0087, "ou": 0.0087, "io": 0.0083, "le": 0.0083,
+	"ve": 0.0083, "co": 0.0079, "me": 0.0079, "de": 0.0076, "hi": 0.0076,
+	"ri": 0.0073, "ro": 0.0073, "ic": 0.0070, "ne": 0.0069, "ea": 0.0069,
+	"ra": 0.0069, "ce": 0.0065, "li": 0.0062, "ch": 0.0060, "ll": 0.0058,
+	"be": 0.0058, "ma": 0.0057, "si": 0.0055, "om": 0.0055, "ur": 0.0054,
+	"ca": 0.0054, "el": 0.0053, "ta": 0.0053, "la": 0.0053, "ns": 0.0051,
+	"di": 0.0050, "fo": 0.0050, "ho": 0.0049, "pe": 0.0049, "ec": 0.0048,
+	"pr": 0.0047, "no": 0.0046, "ct": 0.0046, "us": 0.0045, "ac": 0.0045,
+	"ot": 0.0044, "il": 0.0043, "tr": 0.0043, "ly": 0.0043, "nc": 0.0042,
+	"et": 0.0042, "ut": 0.0042, "ss": 0.0041, "so": 0.0040, "rs": 0.0040,
+	"un": 0.0039, "lo": 0.0039, "wa": 0.0039, "ge": 0.0039, "ie": 0.0038,
+	"wh": 0.0038, "ee": 0.0038, "wi": 0.0038, "em": 0.0038, "ad": 0.0037,
+	"ol": 0.0037, "rt": 0.0037, "po": 0.0036, "we": 0.0036, "na": 0.0035,
+	"ul": 0.0035, "ni": 0.0034, "ts": 0.0034, "mo": 0.0034, "ow": 0.0033,
+	"pa": 0.0032, "im": 0.0032, "mi": 0.0032, "ai": 0.0032, "sh": 0.0031,
+}
+
+var trigramFrequencies = map[string]float64{
+	"the": 0.0181, "and": 0.0073, "ing": 0.0072, "ent": 0.0042, "ion": 0.0042,
+	"her": 0.0036, "for": 0.0034, "tha": 0.0033, "int": 0.0032,
+	"ere": 0.0031, "tio": 0.0031, "ter": 0.0030, "est": 0.0028, "ers": 0.0028,
+	"ati": 0.0026, "hat": 0.0026, "ate": 0.0025, "all": 0.0025, "eth": 0.0024,
+	"hes": 0.0024, "ver": 0.0024, "his": 0.0024, "ith": 0.0021,
+	"oth": 0.0021, "res": 0.0021, "ont": 0.0020,
+	"you": 0.0020, "are": 0.0019, "not": 0.0018, "was": 0.0018, "ome": 0.0017,
+	"our": 0.0017, "ill": 0.0016, "one": 0.0016, "out": 0.0015, "ang": 0.0015,
+	"ove": 0.0014, "igh": 0.0014, "ake": 0.0014, "ive": 0.0014, "can": 0.0013,
+	"hin": 0.0013, "thi": 0.0013, "wit": 0.0013, "men": 0.0013, "com": 0.0012,
+}
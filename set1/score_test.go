@@ -0,0 +1,76 @@
+package set1
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mipearson/matasano"
+)
+
+var scorers = map[string]Scorer{
+	"ChiSquared": ChiSquared,
+	"Bigrams":    Bigrams,
+	"Trigrams":   Trigrams,
+}
+
+func TestScorersDecodeSimpleXorCipher(t *testing.T) {
+	cipher := matasano.Hex("1b37373331363f78151b7f2b783431333d78397828372d363c78373e783a393b3736").Decode()
+	expected := []byte("Cooking MC's like a pound of bacon")
+
+	for name, scorer := range scorers {
+		got := DecodeSimpleXorCipherWith(cipher, scorer).Top(1)[0]
+		if !bytes.Equal(got.plaintext, expected) {
+			t.Errorf("DecodeSimpleXorCipherWith(%s): got %q expected %q", name, got.plaintext, expected)
+		}
+	}
+}
+
+func TestScorersFindSimpleXorCipheredString(t *testing.T) {
+	ciphertexts, err := matasano.LoadHexLines(matasano.Data, "data/set1_challenge4.txt")
+	matasano.CheckErr(err)
+	expected := []byte("Now that the party is jumping\n")
+
+	for name, scorer := range scorers {
+		candidates := Candidates{}
+		for _, cipher := range ciphertexts {
+			candidates = append(candidates, DecodeSimpleXorCipherWith(cipher, scorer)...)
+		}
+		got := candidates.Top(1)[0]
+		if !bytes.Equal(got.plaintext, expected) {
+			t.Errorf("TestScorersFindSimpleXorCipheredString(%s): got %q expected %q", name, got.plaintext, expected)
+		}
+	}
+}
+
+func TestChiSquaredGuessRepeatingKey(t *testing.T) {
+	cipher, err := matasano.LoadBase64File(matasano.Data, "data/set1_challenge6.txt")
+	matasano.CheckErr(err)
+	expected := []byte("Terminator X: Bring the noise")
+
+	got := GuessRepeatingKeyWith(cipher, ChiSquared).Top(1)[0]
+	if !bytes.Equal(got.key, expected) {
+		t.Errorf("GuessRepeatingKeyWith(ChiSquared): got key %q expected %q", got.key, expected)
+	}
+}
+
+func TestScorerOrdering(t *testing.T) {
+	english := []byte("the quick brown fox jumps over the lazy dog")
+	noise := []byte("xq#zk vj!qpw zzx 9@k qjjw ?? xkcd wq pl zz")
+	binary := []byte("the quick brown fox\x00\x01\x02")
+
+	for name, scorer := range scorers {
+		if scorer.Score(english) <= scorer.Score(noise) {
+			t.Errorf("%s: expected English %.4f to outscore noise %.4f", name, scorer.Score(english), scorer.Score(noise))
+		}
+		if scorer.Score(binary) >= scorer.Score(english) {
+			t.Errorf("%s: expected English %.4f to outscore unprintable text %.4f", name, scorer.Score(english), scorer.Score(binary))
+		}
+	}
+
+	if got := ChiSquared.Score(binary); got != 0 {
+		t.Errorf("ChiSquared(%q): got %.4f, expected unprintable text to be rejected", binary, got)
+	}
+	if got := Printable.Score(binary); got <= 0.8 || got >= 1 {
+		t.Errorf("Printable(%q): got %.4f expected between 0.8 and 1", binary, got)
+	}
+}
@@ -2,8 +2,9 @@ package set1
 
 import (
 	"bytes"
+	"crypto/cipher"
+	"io"
 	"sort"
-	"unicode/utf8"
 
 	"github.com/mipearson/matasano"
 )
@@ -14,6 +15,7 @@ const Disqualifiers = "\x00\x01\x02\x03\x04\x05\x06\x07"
 type Candidate struct {
 	plaintext []byte
 	key       []byte
+	score     float64
 }
 
 type Candidates []Candidate
@@ -25,35 +27,31 @@ func (a KeysizeCandidates) Len() int           { return len(a) }
 func (a KeysizeCandidates) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a KeysizeCandidates) Less(i, j int) bool { return a[i].Score < a[j].Score }
 
-func (c *Candidate) Score() int {
-	if !utf8.Valid(c.plaintext) || bytes.IndexAny(c.plaintext, Disqualifiers) != -1 {
-		return 0
-	}
-	score := 0
-	asUpper := bytes.ToUpper(c.plaintext)
-	for _, b := range asUpper {
-		score += scoreOfByte(b)
-	}
-	return score
+func (c Candidate) Plaintext() []byte {
+	return c.plaintext
 }
 
-func scoreOfByte(src byte) int {
-	idx := bytes.IndexByte([]byte(Frequencies), src)
-	if idx == -1 {
-		return 0
-	} else {
-		return idx
-	}
+func (c Candidate) Key() []byte {
+	return c.key
+}
+
+func (c Candidate) Score() float64 {
+	return c.score
 }
 
 func DecodeSimpleXorCipher(cipher []byte) Candidates {
+	return DecodeSimpleXorCipherWith(cipher, DefaultScorer)
+}
+
+func DecodeSimpleXorCipherWith(cipher []byte, scorer Scorer) Candidates {
 	var candidates Candidates
 
-	for i := 0; i < 255; i++ {
-		xor := bytes.Repeat([]byte{byte(i)}, len(cipher))
+	for i := 0; i < 256; i++ {
+		plaintext := singleByteXOR(make([]byte, len(cipher)), cipher, byte(i))
 		candidate := Candidate{
-			plaintext: matasano.Xor(cipher, xor),
+			plaintext: plaintext,
 			key:       []byte{byte(i)},
+			score:     scorer.Score(plaintext),
 		}
 		if candidate.Score() > 0 {
 			candidates = append(candidates, candidate)
@@ -62,6 +60,13 @@ func DecodeSimpleXorCipher(cipher []byte) Candidates {
 	return candidates
 }
 
+func singleByteXOR(dst []byte, src []byte, key byte) []byte {
+	for i, b := range src {
+		dst[i] = b ^ key
+	}
+	return dst
+}
+
 func RepeatingKeyXOR(src []byte, key []byte) []byte {
 	dst := make([]byte, len(src))
 	for i, b := range src {
@@ -71,6 +76,41 @@ func RepeatingKeyXOR(src []byte, key []byte) []byte {
 	return dst
 }
 
+type repeatingKeyStream struct {
+	key []byte
+	pos int
+}
+
+// TryNewRepeatingKeyXORStream returns matasano.ErrInvalidKeyLength for an
+// empty key.
+func TryNewRepeatingKeyXORStream(key []byte) (cipher.Stream, error) {
+	if len(key) == 0 {
+		return nil, matasano.ErrInvalidKeyLength
+	}
+	return &repeatingKeyStream{key: append([]byte{}, key...)}, nil
+}
+
+func NewRepeatingKeyXORStream(key []byte) cipher.Stream {
+	s, err := TryNewRepeatingKeyXORStream(key)
+	matasano.CheckErr(err)
+	return s
+}
+
+func (s *repeatingKeyStream) XORKeyStream(dst, src []byte) {
+	for i, b := range src {
+		dst[i] = b ^ s.key[s.pos]
+		s.pos = (s.pos + 1) % len(s.key)
+	}
+}
+
+func NewRepeatingKeyXORWriter(w io.Writer, key []byte) io.Writer {
+	return cipher.StreamWriter{S: NewRepeatingKeyXORStream(key), W: w}
+}
+
+func NewRepeatingKeyXORReader(r io.Reader, key []byte) io.Reader {
+	return cipher.StreamReader{S: NewRepeatingKeyXORStream(key), R: r}
+}
+
 func HammingDistance(a []byte, b []byte) int {
 	if len(a) != len(b) {
 		panic("len(a) != len(b)")
@@ -97,9 +137,13 @@ type KeysizeCandidate struct {
 type KeysizeCandidates []KeysizeCandidate
 
 func GuessKeysize(cipher []byte) KeysizeCandidates {
+	return GuessKeysizeRange(cipher, MinKeysize, MaxKeysize)
+}
+
+func GuessKeysizeRange(cipher []byte, min int, max int) KeysizeCandidates {
 	candidates := make(KeysizeCandidates, 0)
 
-	for size := MinKeysize; size <= MaxKeysize; size += 1 {
+	for size := min; size <= max; size += 1 {
 		score := BlockDistance(cipher, size)
 		if score != -1 {
 			candidates = append(candidates, KeysizeCandidate{Score: score, Keysize: size})
@@ -126,14 +170,24 @@ func BlockDistance(cipher []byte, size int) float64 {
 	return float64(distance) / float64(size) / float64(iterations)
 }
 
+// Top returns up to count candidates, best first, without reordering a.
 func (a Candidates) Top(count int) Candidates {
-	sort.Sort(sort.Reverse(a))
-	return a[:count]
+	sorted := append(Candidates{}, a...)
+	sort.Stable(sort.Reverse(sorted))
+	if count > len(sorted) {
+		count = len(sorted)
+	}
+	return sorted[:count]
 }
 
+// Top returns up to count keysizes, most likely first, without reordering a.
 func (a KeysizeCandidates) Top(count int) KeysizeCandidates {
-	sort.Sort(a)
-	return a[:count]
+	sorted := append(KeysizeCandidates{}, a...)
+	sort.Stable(sorted)
+	if count > len(sorted) {
+		count = len(sorted)
+	}
+	return sorted[:count]
 }
 
 func (a KeysizeCandidates) ContainsSize(size int) bool {
@@ -154,18 +208,101 @@ func everyNthByte(src []byte, offset int, blocksize int) []byte {
 	return dst
 }
 
+const DefaultKeysizes = 3
+
+// RepeatingKeyOptions tunes GuessRepeatingKeyWithOptions. Zero values
+// fall back to MinKeysize, MaxKeysize, DefaultKeysizes, DefaultScorer and
+// Bigrams.
+type RepeatingKeyOptions struct {
+	MinKeysize int
+	MaxKeysize int
+	// Keysizes is how many of the best-ranked keysizes to solve.
+	Keysizes int
+	// Scorer solves each column of the cipher, so it should work on
+	// non-contiguous text.
+	Scorer Scorer
+	// PlaintextScorer ranks the full plaintexts.
+	PlaintextScorer Scorer
+}
+
+func (o RepeatingKeyOptions) withDefaults() RepeatingKeyOptions {
+	if o.MinKeysize == 0 {
+		o.MinKeysize = MinKeysize
+	}
+	if o.MaxKeysize == 0 {
+		o.MaxKeysize = MaxKeysize
+	}
+	if o.Keysizes == 0 {
+		o.Keysizes = DefaultKeysizes
+	}
+	if o.Scorer == nil {
+		o.Scorer = DefaultScorer
+	}
+	if o.PlaintextScorer == nil {
+		o.PlaintextScorer = Bigrams
+	}
+	return o
+}
+
 func GuessRepeatingKey(cipher []byte) Candidates {
-	keysize := GuessKeysize(cipher).Top(1)[0].Keysize
+	return GuessRepeatingKeyWithOptions(cipher, RepeatingKeyOptions{})
+}
+
+func GuessRepeatingKeyWith(cipher []byte, scorer Scorer) Candidates {
+	return GuessRepeatingKeyWithOptions(cipher, RepeatingKeyOptions{Scorer: scorer, PlaintextScorer: scorer})
+}
+
+// GuessRepeatingKeyWithOptions solves the cipher for each of the most likely
+// keysizes and returns the resulting candidates ranked by the score of their
+// full plaintext. A key that is a shorter key repeated is reported as the
+// shorter key.
+func GuessRepeatingKeyWithOptions(cipher []byte, opts RepeatingKeyOptions) Candidates {
+	opts = opts.withDefaults()
+	keysizes := GuessKeysizeRange(cipher, opts.MinKeysize, opts.MaxKeysize).Top(opts.Keysizes)
+	sort.Slice(keysizes, func(i, j int) bool { return keysizes[i].Keysize < keysizes[j].Keysize })
 
+	candidates := Candidates{}
+	for _, k := range keysizes {
+		key := shortestPeriod(solveRepeatingKey(cipher, k.Keysize, opts.Scorer))
+		plaintext := RepeatingKeyXOR(cipher, key)
+		if candidates.containsPlaintext(plaintext) {
+			continue
+		}
+		candidates = append(candidates, Candidate{
+			key:       key,
+			plaintext: plaintext,
+			score:     opts.PlaintextScorer.Score(plaintext),
+		})
+	}
+	return candidates.Top(len(candidates))
+}
+
+func solveRepeatingKey(cipher []byte, keysize int, scorer Scorer) []byte {
 	key := make([]byte, keysize)
 
 	for i := 0; i < keysize; i++ {
 		slices := everyNthByte(cipher, i, keysize)
-		key[i] = DecodeSimpleXorCipher(slices).Top(1)[0].key[0]
+		if best := DecodeSimpleXorCipherWith(slices, scorer).Top(1); len(best) > 0 {
+			key[i] = best[0].key[0]
+		}
+	}
+	return key
+}
+
+func shortestPeriod(key []byte) []byte {
+	for period := 1; period < len(key); period++ {
+		if len(key)%period == 0 && bytes.Equal(key[period:], key[:len(key)-period]) {
+			return key[:period]
+		}
 	}
-	candidate := Candidate{
-		key:       key,
-		plaintext: RepeatingKeyXOR(cipher, key),
+	return key
+}
+
+func (a Candidates) containsPlaintext(plaintext []byte) bool {
+	for _, c := range a {
+		if bytes.Equal(c.plaintext, plaintext) {
+			return true
+		}
 	}
-	return Candidates{candidate}
+	return false
 }
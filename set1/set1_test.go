@@ -2,9 +2,11 @@ package set1
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"testing"
+	"testing/iotest"
 
 	"github.com/mipearson/matasano"
 )
@@ -13,7 +15,7 @@ func checkForExpectedCandidate(c Candidates, expected Candidate, debug bool) boo
 	found := false
 	for _, candidate := range c {
 		if debug {
-			fmt.Printf("key: %q score: %d plaintext: %q\n", candidate.key, candidate.Score(), candidate.plaintext)
+			fmt.Printf("key: %q score: %.2f plaintext: %q\n", candidate.key, candidate.Score(), candidate.plaintext)
 		}
 		if bytes.Equal(candidate.plaintext, expected.plaintext) && bytes.Equal(candidate.key, expected.key) {
 			found = true
@@ -36,14 +38,12 @@ func TestDecodeSimpleXorCipher(t *testing.T) {
 }
 
 func TestFindSimpleXorCipheredString(t *testing.T) {
-	file, err := ioutil.ReadFile("../data/set1_challenge4.txt")
+	ciphertexts, err := matasano.LoadHexLines(matasano.Data, "data/set1_challenge4.txt")
 	matasano.CheckErr(err)
-	ciphertexts := bytes.Split(file, []byte{'\n'})
 
 	candidates := Candidates{}
-	for _, text := range ciphertexts {
-		cipher := matasano.Hex(bytes.TrimSpace(text))
-		candidates = append(candidates, DecodeSimpleXorCipher(cipher.Decode())...)
+	for _, cipher := range ciphertexts {
+		candidates = append(candidates, DecodeSimpleXorCipher(cipher)...)
 	}
 	expected := Candidate{
 		plaintext: []byte("Now that the party is jumping\n"),
@@ -76,6 +76,28 @@ var repeatingKeyCases = []struct {
 	},
 }
 
+func TestCandidatesTop(t *testing.T) {
+	candidates := Candidates{
+		{plaintext: []byte("b"), key: []byte{2}, score: 2},
+		{plaintext: []byte("c"), key: []byte{3}, score: 3},
+		{plaintext: []byte("a"), key: []byte{1}, score: 1},
+	}
+
+	got := candidates.Top(5)
+	if len(got) != 3 || got[0].Score() != 3 || got[2].Score() != 1 {
+		t.Errorf("Candidates.Top(5): got %+v, expected all three best first", got)
+	}
+	if !bytes.Equal(got[0].Plaintext(), []byte("c")) || !bytes.Equal(got[0].Key(), []byte{3}) {
+		t.Errorf("Candidates.Top(5)[0]: got plaintext %q key %q", got[0].Plaintext(), got[0].Key())
+	}
+	if candidates[0].Score() != 2 {
+		t.Errorf("Candidates.Top sorted the receiver in place: %+v", candidates)
+	}
+	if got := (Candidates{}).Top(1); len(got) != 0 {
+		t.Errorf("Candidates{}.Top(1): got %+v, expected none", got)
+	}
+}
+
 func TestRepeatingKeyXor(t *testing.T) {
 	for _, test := range repeatingKeyCases {
 		got := matasano.ToBase64(RepeatingKeyXOR(test.plaintext, test.key))
@@ -86,6 +108,31 @@ func TestRepeatingKeyXor(t *testing.T) {
 
 }
 
+func TestRepeatingKeyXorStream(t *testing.T) {
+	for _, test := range repeatingKeyCases {
+		var buf bytes.Buffer
+		w := NewRepeatingKeyXORWriter(&buf, test.key)
+		for _, b := range test.plaintext {
+			_, err := w.Write([]byte{b})
+			matasano.CheckErr(err)
+		}
+		if !bytes.Equal(buf.Bytes(), test.cipher.Decode()) {
+			t.Errorf("TestRepeatingKeyXorStream(%q, %q) got %q, expected %q", test.plaintext, test.key, matasano.ToBase64(buf.Bytes()), test.cipher)
+		}
+
+		got, err := ioutil.ReadAll(NewRepeatingKeyXORReader(iotest.HalfReader(&buf), test.key))
+		if err != nil || !bytes.Equal(got, test.plaintext) {
+			t.Errorf("TestRepeatingKeyXorStream reader got %q, %v expected %q", got, err, test.plaintext)
+		}
+	}
+}
+
+func TestRepeatingKeyXorStreamEmptyKey(t *testing.T) {
+	if _, err := TryNewRepeatingKeyXORStream(nil); !errors.Is(err, matasano.ErrInvalidKeyLength) {
+		t.Errorf("TryNewRepeatingKeyXORStream(nil): got %v, expected ErrInvalidKeyLength", err)
+	}
+}
+
 func TestHammingDistance(t *testing.T) {
 	a := []byte("this is a test")
 	b := []byte("wokka wokka!!!")
@@ -109,10 +156,8 @@ func TestGuessKeysize(t *testing.T) {
 }
 
 func TestGuessRepeatingKey(t *testing.T) {
-	text, err := ioutil.ReadFile("../data/set1_challenge6.txt")
+	cipher, err := matasano.LoadBase64File(matasano.Data, "data/set1_challenge6.txt")
 	matasano.CheckErr(err)
-
-	cipher := matasano.Base64(text).Decode()
 	candidates := GuessRepeatingKey(cipher)
 	expected := Candidate{
 		key:       []byte("Terminator X: Bring the noise"),
@@ -124,10 +169,32 @@ func TestGuessRepeatingKey(t *testing.T) {
 	}
 }
 
+func TestGuessRepeatingKeyTriesMoreKeysizes(t *testing.T) {
+	test := repeatingKeyCases[0]
+	cipher := test.cipher.Decode()
+
+	// The premise: on this short cipher the Hamming-distance heuristic
+	// does not rank the true keysize first.
+	if GuessKeysize(cipher).Top(1)[0].Keysize == len(test.key) {
+		t.Fatalf("TestGuessRepeatingKeyTriesMoreKeysizes: expected GuessKeysize to rank %d below first", len(test.key))
+	}
+
+	got := GuessRepeatingKeyWithOptions(cipher, RepeatingKeyOptions{Scorer: ChiSquared}).Top(1)[0]
+	if !bytes.Equal(got.Key(), test.key) || !bytes.Equal(got.Plaintext(), test.plaintext) {
+		t.Errorf("TestGuessRepeatingKeyTriesMoreKeysizes: got key %q, expected %q", got.Key(), test.key)
+	}
+
+	candidates := GuessRepeatingKeyWithOptions(cipher, RepeatingKeyOptions{MinKeysize: 5, MaxKeysize: 7, Keysizes: 10})
+	for _, c := range candidates {
+		if len(c.Key()) < 5 || len(c.Key()) > 7 {
+			t.Errorf("TestGuessRepeatingKeyTriesMoreKeysizes: got keysize %d outside 5..7", len(c.Key()))
+		}
+	}
+}
+
 func TestDecryptAndEncryptAESECB(t *testing.T) {
-	text, err := ioutil.ReadFile("../data/set1_challenge7.txt")
+	cipher, err := matasano.LoadBase64File(matasano.Data, "data/set1_challenge7.txt")
 	matasano.CheckErr(err)
-	cipher := matasano.Base64(text).Decode()
 	key := []byte("YELLOW SUBMARINE")
 	expected := []byte("I'm back and I'm ringin' the bell \nA rockin' on the mike while the fly girls yell \nIn ecstasy in the back of me \nWell that's my DJ Deshay cuttin' all them Z's \nHittin' hard and the girlies goin' crazy \nVanilla's on the mike, man I'm not lazy. \n\nI'm lettin' my drug kick in \nIt controls my mouth and I begin \nTo just let it flow, let my concepts go \nMy posse's to the side yellin', Go Vanilla Go! \n\nSmooth 'cause that's the way I will be \nAnd if you don't give a damn, then \nWhy you starin' at me \nSo get off 'cause I control the stage \nThere's no dissin' allowed \nI'm in my own phase \nThe girlies sa y they love me and that is ok \nAnd I can dance better than any kid n' play \n\nStage 2 -- Yea the one ya' wanna listen to \nIt's off my head so let the beat play through \nSo I can funk it up and make it sound good \n1-2-3 Yo -- Knock on some wood \nFor good luck, I like my rhymes atrocious \nSupercalafragilisticexpialidocious \nI'm an effect and that you can bet \nI can take a fly girl and make her wet. \n\nI'm like Samson -- Samson to Delilah \nThere's no denyin', You can try to hang \nBut you'll keep tryin' to get my style \nOver and over, practice makes perfect \nBut not if you're a loafer. \n\nYou'll get nowhere, no place, no time, no girls \nSoon -- Oh my God, homebody, you probably eat \nSpaghetti with a spoon! Come on and say it! \n\nVIP. Vanilla Ice yep, yep, I'm comin' hard like a rhino \nIntoxicating so you stagger like a wino \nSo punks stop trying and girl stop cryin' \nVanilla Ice is sellin' and you people are buyin' \n'Cause why the freaks are jockin' like Crazy Glue \nMovin' and groovin' trying to sing along \nAll through the ghetto groovin' this here song \nNow you're amazed by the VIP posse. \n\nSteppin' so hard like a German Nazi \nStartled by the bases hittin' ground \nThere's no trippin' on mine, I'm just gettin' down \nSparkamatic, I'm hangin' tight like a fanatic \nYou trapped me once and I thought that \nYou might have it \nSo step down and lend me your ear \n'89 in my time! You, '90 is my year. \n\nYou're weakenin' fast, YO! and I can tell it \nYour body's gettin' hot, so, so I can smell it \nSo don't be mad and don't be sad \n'Cause the lyrics belong to ICE, You can call me Dad \nYou're pitchin' a fit, so step back and endure \nLet the witch doctor, Ice, do the dance to cure \nSo come up close and don't be square \nYou wanna battle me -- Anytime, anywhere \n\nYou thought that I was weak, Boy, you're dead wrong \nSo come on, everybody and sing this song \n\nSay -- Play that funky music Say, go white boy, go white boy go \nplay that funky music Go white boy, go white boy, go \nLay down and boogie and play that funky music till you die. \n\nPlay that funky music Come on, Come on, let me hear \nPlay that funky music white boy you say it, say it \nPlay that funky music A little louder now \nPlay that funky music, white boy Come on, Come on, Come on \nPlay that funky music \n\x04\x04\x04\x04")
 
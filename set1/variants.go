@@ -0,0 +1,97 @@
+package set1
+
+import (
+	"github.com/mipearson/matasano"
+)
+
+// RollingKeyXOR XORs src[i] with f(i).
+func RollingKeyXOR(src []byte, f func(i int) byte) []byte {
+	dst := make([]byte, len(src))
+	for i, b := range src {
+		dst[i] = b ^ f(i)
+	}
+	return dst
+}
+
+// LinearRollingKey is the key schedule key[i] = start + step*i mod 256.
+func LinearRollingKey(start byte, step byte) func(int) byte {
+	return func(i int) byte {
+		return start + step*byte(i)
+	}
+}
+
+// BreakLinearRollingXOR tries every LinearRollingKey. Schedules that turn
+// any byte unprintable are discarded before scoring. Each candidate's key
+// is {start, step}.
+func BreakLinearRollingXOR(cipher []byte, scorer Scorer) Candidates {
+	var candidates Candidates
+	plaintext := make([]byte, len(cipher))
+
+	for step := 0; step < 256; step++ {
+	nextStart:
+		for start := 0; start < 256; start++ {
+			k := byte(start)
+			for i, b := range cipher {
+				plaintext[i] = b ^ k
+				if !isPrintable(plaintext[i]) {
+					continue nextStart
+				}
+				k += byte(step)
+			}
+			candidate := Candidate{
+				plaintext: append([]byte{}, plaintext...),
+				key:       []byte{byte(start), byte(step)},
+				score:     scorer.Score(plaintext),
+			}
+			if candidate.score > 0 {
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+	return candidates
+}
+
+// BreakRollingRepeatingKeyXOR breaks a repeating key that is also XORed
+// with a known rolling term, key[i] = base[i%n] ^ roll(i), by removing the
+// rolling term and solving what is left as repeating-key XOR.
+func BreakRollingRepeatingKeyXOR(cipher []byte, roll func(int) byte, opts RepeatingKeyOptions) Candidates {
+	return GuessRepeatingKeyWithOptions(RollingKeyXOR(cipher, roll), opts)
+}
+
+// AutokeyXOR chains each byte into the next: c[i] = p[i] ^ key[i%n] ^ c[i-1],
+// with c[-1] taken as zero.
+func AutokeyXOR(src []byte, key []byte) []byte {
+	dst := make([]byte, len(src))
+	var prev byte
+	for i, b := range src {
+		dst[i] = b ^ key[i%len(key)] ^ prev
+		prev = dst[i]
+	}
+	return dst
+}
+
+func AutokeyXORDecrypt(cipher []byte, key []byte) []byte {
+	return RepeatingKeyXOR(unchainAutokey(cipher), key)
+}
+
+// unchainAutokey undoes the ciphertext feedback, leaving plain
+// repeating-key XOR.
+func unchainAutokey(cipher []byte) []byte {
+	dst := make([]byte, len(cipher))
+	var prev byte
+	for i, b := range cipher {
+		dst[i] = b ^ prev
+		prev = b
+	}
+	return dst
+}
+
+func BreakAutokeyXOR(cipher []byte, opts RepeatingKeyOptions) Candidates {
+	return GuessRepeatingKeyWithOptions(unchainAutokey(cipher), opts)
+}
+
+// RunningKeyXOR XORs src with a key at least as long as itself, usually
+// another piece of text.
+func RunningKeyXOR(src []byte, key []byte) []byte {
+	return matasano.Xor(src, key)
+}
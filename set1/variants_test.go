@@ -0,0 +1,51 @@
+package set1
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mipearson/matasano"
+)
+
+func lyrics() []byte {
+	cipher, err := matasano.LoadBase64File(matasano.Data, "data/set1_challenge7.txt")
+	matasano.CheckErr(err)
+	return matasano.StripPadding(matasano.DecryptAESECB(cipher, []byte("YELLOW SUBMARINE")), 16)
+}
+
+func TestBreakLinearRollingXOR(t *testing.T) {
+	plaintext := []byte("Now that the party is jumping")
+	cipher := RollingKeyXOR(plaintext, LinearRollingKey(17, 93))
+
+	got := BreakLinearRollingXOR(cipher, ChiSquared).Top(1)
+	if len(got) == 0 || !bytes.Equal(got[0].Key(), []byte{17, 93}) || !bytes.Equal(got[0].Plaintext(), plaintext) {
+		t.Errorf("BreakLinearRollingXOR: got %+v, expected key {17, 93}", got)
+	}
+}
+
+func TestBreakRollingRepeatingKeyXOR(t *testing.T) {
+	plaintext := lyrics()[:600]
+	key := []byte("Vanilla")
+	roll := func(i int) byte { return byte(i) }
+	cipher := RollingKeyXOR(RepeatingKeyXOR(plaintext, key), roll)
+
+	got := BreakRollingRepeatingKeyXOR(cipher, roll, RepeatingKeyOptions{Scorer: ChiSquared}).Top(1)
+	if len(got) == 0 || !bytes.Equal(got[0].Key(), key) {
+		t.Errorf("BreakRollingRepeatingKeyXOR: got %+v, expected key %q", got, key)
+	}
+}
+
+func TestAutokeyXOR(t *testing.T) {
+	plaintext := lyrics()
+	key := []byte("Terminator X")
+	cipher := AutokeyXOR(plaintext, key)
+
+	if got := AutokeyXORDecrypt(cipher, key); !bytes.Equal(got, plaintext) {
+		t.Errorf("AutokeyXORDecrypt: got %q expected %q", got[:40], plaintext[:40])
+	}
+
+	got := BreakAutokeyXOR(cipher, RepeatingKeyOptions{}).Top(1)
+	if len(got) == 0 || !bytes.Equal(got[0].Key(), key) || !bytes.Equal(got[0].Plaintext(), plaintext) {
+		t.Errorf("BreakAutokeyXOR: got %+v, expected key %q", got, key)
+	}
+}
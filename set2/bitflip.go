@@ -0,0 +1,89 @@
+package set2
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/mipearson/matasano"
+)
+
+var ErrBitflipRange = errors.New("set2: bitflip target has no preceding ciphertext block")
+
+// CBCBitflip returns a copy of ciphertext that decrypts with want in place
+// of known at plaintext offset, by XORing the difference into the previous
+// ciphertext block. The blocks holding that previous ciphertext decrypt to
+// garbage. offset counts from the start of ciphertext, so it must be at
+// least one block in; to change the first block, flip the IV instead.
+func CBCBitflip(ciphertext []byte, blocksize int, offset int, known []byte, want []byte) ([]byte, error) {
+	if len(known) != len(want) {
+		return nil, matasano.ErrLengthMismatch
+	}
+	if offset < blocksize || offset+len(known) > len(ciphertext) {
+		return nil, ErrBitflipRange
+	}
+
+	flipped := append([]byte{}, ciphertext...)
+	delta, err := matasano.TryXor(known, want)
+	if err != nil {
+		return nil, err
+	}
+	for i, d := range delta {
+		flipped[offset-blocksize+i] ^= d
+	}
+	return flipped, nil
+}
+
+var (
+	challenge16Prefix = []byte("comment1=cooking%20MCs;userdata=")
+	challenge16Suffix = []byte(";comment2=%20like%20a%20pound%20of%20bacon")
+	challenge16Codec  = Codec{Separator: ';', Assign: '=', Quoting: QuotePercent}
+)
+
+// Challenge16 is the challenge 16 target: it CBC-encrypts user data between
+// a fixed prefix and suffix, escaping ';' and '=' so the data cannot add
+// fields, and grants admin if ";admin=true;" appears after decryption.
+type Challenge16 struct {
+	key []byte
+	iv  []byte
+}
+
+func NewChallenge16() *Challenge16 {
+	return &Challenge16{key: matasano.RandBytes(16), iv: matasano.RandBytes(16)}
+}
+
+// Challenge16Plaintext wraps userdata between the challenge 16 prefix and
+// suffix, escaping ';' and '='.
+func Challenge16Plaintext(userdata []byte) []byte {
+	quoted, err := challenge16Codec.Quote(userdata)
+	matasano.CheckErr(err)
+	return bytes.Join([][]byte{challenge16Prefix, quoted, challenge16Suffix}, []byte{})
+}
+
+func HasAdminField(plaintext []byte) bool {
+	return bytes.Contains(plaintext, []byte(";admin=true;"))
+}
+
+func (c *Challenge16) Encrypt(userdata []byte) []byte {
+	return matasano.EncryptAESCBC(matasano.Pkcs7Padding(Challenge16Plaintext(userdata), 16), c.key, c.iv)
+}
+
+func (c *Challenge16) IsAdmin(ciphertext []byte) (bool, error) {
+	padded, err := matasano.TryDecryptAESCBC(ciphertext, c.key, c.iv)
+	if err != nil {
+		return false, err
+	}
+	plaintext, err := matasano.Pkcs7Unpad(padded, 16)
+	if err != nil {
+		return false, err
+	}
+	return HasAdminField(plaintext), nil
+}
+
+// Set2Challenge16ForceAdmin sends a block of filler that it then flips
+// into ";admin=true;" by editing the ciphertext of the prefix's last block.
+func Set2Challenge16ForceAdmin(c *Challenge16) ([]byte, error) {
+	want := []byte(";admin=true;")
+	known := bytes.Repeat([]byte("A"), len(want))
+	cipher := c.Encrypt(known)
+	return CBCBitflip(cipher, 16, len(challenge16Prefix), known, want)
+}
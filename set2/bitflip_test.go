@@ -0,0 +1,63 @@
+package set2
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mipearson/matasano"
+)
+
+func TestCBCBitflip(t *testing.T) {
+	key, iv := matasano.RandBytes(16), matasano.RandBytes(16)
+	plaintext := []byte("0123456789abcdefYELLOW SUBMARINE")
+	cipher := matasano.EncryptAESCBC(plaintext, key, iv)
+
+	flipped, err := CBCBitflip(cipher, 16, 23, []byte("SUB"), []byte("sub"))
+	if err != nil {
+		t.Fatalf("TestCBCBitflip: %v", err)
+	}
+	got := matasano.DecryptAESCBC(flipped, key, iv)
+	if !bytes.Equal(got[16:], []byte("YELLOW subMARINE")) {
+		t.Errorf("TestCBCBitflip: got %q expected %q", got[16:], "YELLOW subMARINE")
+	}
+	if bytes.Equal(got[:16], plaintext[:16]) {
+		t.Errorf("TestCBCBitflip: expected the previous block to be scrambled")
+	}
+	if !bytes.Equal(cipher, matasano.EncryptAESCBC(plaintext, key, iv)) {
+		t.Errorf("TestCBCBitflip: the input ciphertext was modified")
+	}
+}
+
+func TestCBCBitflipErrors(t *testing.T) {
+	cipher := make([]byte, 32)
+	if _, err := CBCBitflip(cipher, 16, 4, []byte("a"), []byte("b")); err != ErrBitflipRange {
+		t.Errorf("TestCBCBitflipErrors: got %v expected %v", err, ErrBitflipRange)
+	}
+	if _, err := CBCBitflip(cipher, 16, 30, []byte("abc"), []byte("xyz")); err != ErrBitflipRange {
+		t.Errorf("TestCBCBitflipErrors: got %v expected %v", err, ErrBitflipRange)
+	}
+	if _, err := CBCBitflip(cipher, 16, 16, []byte("ab"), []byte("x")); !errors.Is(err, matasano.ErrLengthMismatch) {
+		t.Errorf("TestCBCBitflipErrors: got %v expected %v", err, matasano.ErrLengthMismatch)
+	}
+}
+
+func TestChallenge16QuotesUserdata(t *testing.T) {
+	c := NewChallenge16()
+	admin, err := c.IsAdmin(c.Encrypt([]byte(";admin=true;")))
+	if err != nil || admin {
+		t.Errorf("TestChallenge16QuotesUserdata: got %v, %v expected false", admin, err)
+	}
+}
+
+func TestSet2Challenge16ForceAdmin(t *testing.T) {
+	c := NewChallenge16()
+	forged, err := Set2Challenge16ForceAdmin(c)
+	if err != nil {
+		t.Fatalf("TestSet2Challenge16ForceAdmin: %v", err)
+	}
+	admin, err := c.IsAdmin(forged)
+	if err != nil || !admin {
+		t.Errorf("TestSet2Challenge16ForceAdmin: got %v, %v expected true", admin, err)
+	}
+}
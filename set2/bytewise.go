@@ -0,0 +1,95 @@
+package set2
+
+import (
+	"bytes"
+	"context"
+)
+
+// ByteAtATimeOptions tune how DecryptECBSuffixWith spends oracle queries.
+// With Batch, all 256 guesses for a byte are packed into consecutive blocks
+// of a single query, together with the filler that lines the target byte
+// up, so each recovered byte costs one query instead of up to 257. With
+// Cache, identical queries are answered from memory instead of being sent
+// again.
+type ByteAtATimeOptions struct {
+	Batch bool
+	Cache bool
+}
+
+// AttackStats counts the queries an attack sent to the oracle, including
+// those spent discovering the block size and lining up a prefix, and how
+// many were answered from the cache instead.
+type AttackStats struct {
+	Queries   int
+	CacheHits int
+}
+
+// DecryptECBSuffixWith is DecryptECBSuffix with control over how queries
+// are made, and reports how many were needed. Queries are counted and
+// cached with CountQueries and CacheQueries.
+func DecryptECBSuffixWith(e Encrypter, opts ByteAtATimeOptions) ([]byte, AttackStats, error) {
+	ctx := context.Background()
+	counter := &QueryCounter{}
+	var secret []byte
+	var attackErr error
+	err := RunWithOracle(ctx, CountQueries(EncrypterOracle(e), counter), func(e Encrypter) {
+		secret, attackErr = decryptECBSuffixWith(ctx, e, opts, counter)
+	})
+	if err == nil {
+		err = attackErr
+	}
+	stats := AttackStats{Queries: int(counter.Queries()), CacheHits: int(counter.Hits())}
+	if err != nil {
+		return nil, stats, err
+	}
+	return secret, stats, nil
+}
+
+func decryptECBSuffixWith(ctx context.Context, e Encrypter, opts ByteAtATimeOptions, counter *QueryCounter) ([]byte, error) {
+	blocksize, err := e.DiscoverBlocksize()
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsECB(blocksize) {
+		return nil, ErrNotECB
+	}
+
+	var aligned Encrypter
+	if prefixLen, ok := e.fixedPrefixLength(blocksize); ok {
+		aligned = e.strippedEncrypter(blocksize, prefixLen)
+	} else {
+		aligned = e.alignedEncrypter(blocksize)
+	}
+	if opts.Cache {
+		aligned = oracleEncrypter(ctx, CacheQueries(EncrypterOracle(aligned), counter))
+	}
+	next := aligned.discoverNextByte
+	if opts.Batch {
+		next = aligned.discoverNextByteBatched
+	}
+	return recoverSuffix(blocksize, next), nil
+}
+
+// discoverNextByteBatched finds the next byte of the secret with a single
+// query: a dictionary of all 256 candidate blocks followed by the filler
+// that pushes the unknown byte to the end of a block.
+func (e Encrypter) discoverNextByteBatched(keysize int, known []byte) (byte, bool) {
+	prefix, candidate := discoveryPrefix(keysize, known)
+	dictionary := make([]byte, 0, 256*keysize)
+	for i := 0; i < 256; i++ {
+		dictionary = append(append(dictionary, candidate...), byte(i))
+	}
+
+	cipher := e(bytes.Join([][]byte{dictionary, prefix}, []byte{}))
+	blockAt := len(dictionary) + len(known) - (len(known) % keysize)
+	if blockAt+keysize > len(cipher) {
+		return 0, false
+	}
+	target := cipher[blockAt : blockAt+keysize]
+	for i := 0; i < 256; i++ {
+		if bytes.Equal(cipher[i*keysize:(i+1)*keysize], target) {
+			return byte(i), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,38 @@
+package set2
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecryptECBSuffixWith(t *testing.T) {
+	prefixed := PrefixedEncrypter(Set2Challenge12Crypt, func() []byte { return RandomPrefix(40) })
+	for _, e := range []Encrypter{Set2Challenge12Crypt, Set2Challenge14Crypt, prefixed} {
+		for _, opts := range []ByteAtATimeOptions{{}, {Cache: true}, {Batch: true}, {Batch: true, Cache: true}} {
+			got, _, err := DecryptECBSuffixWith(e, opts)
+			if err != nil {
+				t.Fatalf("TestDecryptECBSuffixWith(%+v): %v", opts, err)
+			}
+			if !bytes.Equal(got, challenge12Suffix) {
+				t.Errorf("TestDecryptECBSuffixWith(%+v): got %q expected %q", opts, got, challenge12Suffix)
+			}
+		}
+	}
+}
+
+func TestDecryptECBSuffixWithQueries(t *testing.T) {
+	_, plain, _ := DecryptECBSuffixWith(Set2Challenge12Crypt, ByteAtATimeOptions{})
+	_, cached, _ := DecryptECBSuffixWith(Set2Challenge12Crypt, ByteAtATimeOptions{Cache: true})
+	_, batched, _ := DecryptECBSuffixWith(Set2Challenge12Crypt, ByteAtATimeOptions{Batch: true})
+
+	if cached.CacheHits == 0 || cached.Queries+cached.CacheHits != plain.Queries {
+		t.Errorf("TestDecryptECBSuffixWithQueries: cached %+v, uncached %+v", cached, plain)
+	}
+
+	// One query per byte recovered, plus the trailing padding byte and the
+	// failed attempt after it, plus discovery.
+	limit := len(challenge12Suffix) + 2 + 64 + 1 + maxAlignmentProbes
+	if batched.Queries > limit || batched.Queries*10 > plain.Queries {
+		t.Errorf("TestDecryptECBSuffixWithQueries: batched %+v, uncached %+v", batched, plain)
+	}
+}
@@ -0,0 +1,142 @@
+package set2
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMalformedField = errors.New("set2: malformed key=value field")
+	ErrMetacharacter  = errors.New("set2: value contains a separator or assignment character")
+	ErrDuplicateField = errors.New("set2: duplicate field")
+)
+
+// Quoting controls what a Codec does with separator and assignment
+// characters that appear inside keys or values.
+type Quoting int
+
+const (
+	// QuoteStrip silently removes them.
+	QuoteStrip Quoting = iota
+	// QuotePercent escapes them, and '%' itself, as %XX.
+	QuotePercent
+	// QuoteReject refuses to encode them.
+	QuoteReject
+)
+
+type Field struct {
+	Key   string
+	Value []byte
+}
+
+// Fields is an ordered list of key=value pairs.
+type Fields []Field
+
+func (f Fields) Get(key string) ([]byte, bool) {
+	for _, field := range f {
+		if field.Key == key {
+			return field.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Codec encodes ordered fields as key=value pairs joined by a separator,
+// such as "email=foo@bar.com&uid=10&role=user".
+type Codec struct {
+	Separator byte
+	Assign    byte
+	Quoting   Quoting
+}
+
+var CookieCodec = Codec{Separator: '&', Assign: '=', Quoting: QuoteStrip}
+
+func (c Codec) Encode(fields Fields) ([]byte, error) {
+	parts := make([][]byte, 0, len(fields))
+	for _, f := range fields {
+		key, err := c.Quote([]byte(f.Key))
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.Quote(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, bytes.Join([][]byte{key, value}, []byte{c.Assign}))
+	}
+	return bytes.Join(parts, []byte{c.Separator}), nil
+}
+
+// Decode parses src strictly: every segment must contain the assignment
+// character and, when percent quoting, every escape must be well formed.
+func (c Codec) Decode(src []byte) (Fields, error) {
+	fields := Fields{}
+	if len(src) == 0 {
+		return fields, nil
+	}
+	for i, segment := range bytes.Split(src, []byte{c.Separator}) {
+		kv := bytes.SplitN(segment, []byte{c.Assign}, 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%w: segment %d %q has no %q", ErrMalformedField, i, segment, c.Assign)
+		}
+		key, err := c.Unquote(kv[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := c.Unquote(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, Field{string(key), value})
+	}
+	return fields, nil
+}
+
+func (c Codec) isMeta(b byte) bool {
+	return b == c.Separator || b == c.Assign || (c.Quoting == QuotePercent && b == '%')
+}
+
+// Quote applies c's quoting to a single key or value.
+func (c Codec) Quote(src []byte) ([]byte, error) {
+	out := make([]byte, 0, len(src))
+	for _, b := range src {
+		if !c.isMeta(b) {
+			out = append(out, b)
+			continue
+		}
+		switch c.Quoting {
+		case QuoteStrip:
+		case QuotePercent:
+			out = append(out, fmt.Sprintf("%%%02X", b)...)
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrMetacharacter, src)
+		}
+	}
+	return out, nil
+}
+
+// Unquote reverses Quote where that is possible.
+func (c Codec) Unquote(src []byte) ([]byte, error) {
+	if c.Quoting != QuotePercent {
+		return src, nil
+	}
+	out := make([]byte, 0, len(src))
+	for i := 0; i < len(src); i++ {
+		if src[i] != '%' {
+			out = append(out, src[i])
+			continue
+		}
+		if i+2 >= len(src) {
+			return nil, fmt.Errorf("%w: truncated escape in %q", ErrMalformedField, src)
+		}
+		b := make([]byte, 1)
+		if _, err := hex.Decode(b, src[i+1:i+3]); err != nil {
+			return nil, fmt.Errorf("%w: bad escape in %q", ErrMalformedField, src)
+		}
+		out = append(out, b[0])
+		i += 2
+	}
+	return out, nil
+}
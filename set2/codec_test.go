@@ -0,0 +1,84 @@
+package set2
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCodecEncode(t *testing.T) {
+	fields := Fields{{"email", []byte("a&b=c%d")}, {"uid", []byte("10")}}
+	cases := []struct {
+		quoting  Quoting
+		expected string
+		err      error
+	}{
+		{QuoteStrip, "email=abc%d&uid=10", nil},
+		{QuotePercent, "email=a%26b%3Dc%25d&uid=10", nil},
+		{QuoteReject, "", ErrMetacharacter},
+	}
+
+	for _, c := range cases {
+		got, err := Codec{'&', '=', c.quoting}.Encode(fields)
+		if !errors.Is(err, c.err) || string(got) != c.expected {
+			t.Errorf("TestCodecEncode(%d): got %q, %v expected %q, %v", c.quoting, got, err, c.expected, c.err)
+		}
+	}
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := Codec{';', '=', QuotePercent}
+	fields := Fields{{"comment1", []byte("a;b=c")}, {"x%", []byte("")}, {"role", []byte("user")}}
+	encoded, err := codec.Encode(fields)
+	if err != nil {
+		t.Fatalf("TestCodecRoundTrip: %v", err)
+	}
+	got, err := codec.Decode(encoded)
+	if err != nil || !reflect.DeepEqual(got, fields) {
+		t.Errorf("TestCodecRoundTrip: got %q, %v expected %q", got, err, fields)
+	}
+	if v, ok := got.Get("role"); !ok || string(v) != "user" {
+		t.Errorf("TestCodecRoundTrip: Get(role) got %q, %v", v, ok)
+	}
+}
+
+func TestCodecDecodeErrors(t *testing.T) {
+	for _, src := range []string{"a=1&b", "a=1&&b=2", "a=%2", "a=%zz"} {
+		if _, err := (Codec{'&', '=', QuotePercent}).Decode([]byte(src)); !errors.Is(err, ErrMalformedField) {
+			t.Errorf("TestCodecDecodeErrors(%q): got %v expected %v", src, err, ErrMalformedField)
+		}
+	}
+}
+
+func TestParseProfile(t *testing.T) {
+	got, err := ParseProfile([]byte("email=foo@bar.com&uid=10&role=user"))
+	expected := Profile{"email": []byte("foo@bar.com"), "uid": []byte("10"), "role": []byte("user")}
+	if err != nil || !reflect.DeepEqual(got, expected) {
+		t.Errorf("TestParseProfile: got %v, %v expected %v", got, err, expected)
+	}
+
+	if _, err := ParseProfile([]byte("role=user&role=admin")); !errors.Is(err, ErrDuplicateField) {
+		t.Errorf("TestParseProfile: got %v expected %v", err, ErrDuplicateField)
+	}
+	if _, err := ParseProfile([]byte("email=foo&junk")); !errors.Is(err, ErrMalformedField) {
+		t.Errorf("TestParseProfile: got %v expected %v", err, ErrMalformedField)
+	}
+}
+
+func TestBytesToProfileLenient(t *testing.T) {
+	got := BytesToProfile([]byte("email=foo&junk&role=user"))
+	expected := Profile{"email": []byte("foo"), "role": []byte("user")}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("TestBytesToProfileLenient: got %v expected %v", got, expected)
+	}
+}
+
+func TestProfileAsBytesOrder(t *testing.T) {
+	p := Profile{"zz": []byte("1"), "role": []byte("user"), "email": []byte("e"), "aa": []byte("2"), "uid": []byte("10")}
+	expected := "email=e&uid=10&role=user&aa=2&zz=1"
+	for i := 0; i < 20; i++ {
+		if got := string(p.AsBytes()); got != expected {
+			t.Fatalf("TestProfileAsBytesOrder: got %q expected %q", got, expected)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package set2
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/mipearson/matasano"
+)
+
+var ErrUnexpectedLayout = errors.New("set2: oracle output is shorter than the described layout")
+
+// CutAndPaste describes an ECB oracle that encrypts Before, then the
+// attacker's input, then After, then a value the attacker wants to replace
+// with Value. Filler is a byte the oracle passes through unquoted. Old is
+// the value being replaced; ForgeField needs its length to find the fields
+// after it, and ForgeLastField ignores it.
+type CutAndPaste struct {
+	Before []byte
+	After  []byte
+	Value  []byte
+	Filler byte
+	Old    []byte
+}
+
+// ForgeLastField builds a ciphertext for which the oracle's final value is
+// replaced by t.Value. It encrypts Value with its own PKCS#7 padding at a
+// block boundary, then asks for an encryption where After ends on a block
+// boundary and swaps everything from there on for the forged blocks.
+func ForgeLastField(oracle Encrypter, t CutAndPaste) ([]byte, error) {
+	bs := oracle.DiscoverKeysize()
+	if bs < 2 {
+		return nil, ErrNoBlockSize
+	}
+
+	value, err := t.encryptBlocks(oracle, bs, matasano.Pkcs7Padding(t.Value, bs))
+	if err != nil {
+		return nil, err
+	}
+	head, _, err := t.splitAfter(oracle, bs, len(t.After))
+	if err != nil {
+		return nil, err
+	}
+	return append(head, value...), nil
+}
+
+// ForgeField is ForgeLastField for a value that may have more fields after
+// it. Those fields are cut from an encryption where they start on a block
+// boundary and spliced back after the forged blocks, so the forged value
+// must also end on one: Value is extended with Filler to a whole number of
+// blocks, and the application has to accept or trim the extra bytes.
+func ForgeField(oracle Encrypter, t CutAndPaste) ([]byte, error) {
+	bs := oracle.DiscoverKeysize()
+	if bs < 2 {
+		return nil, ErrNoBlockSize
+	}
+
+	fill := (bs - len(t.Value)%bs) % bs
+	value, err := t.encryptBlocks(oracle, bs, append(append([]byte{}, t.Value...), bytes.Repeat([]byte{t.Filler}, fill)...))
+	if err != nil {
+		return nil, err
+	}
+	head, _, err := t.splitAfter(oracle, bs, len(t.After))
+	if err != nil {
+		return nil, err
+	}
+	_, tail, err := t.splitAfter(oracle, bs, len(t.After)+len(t.Old))
+	if err != nil {
+		return nil, err
+	}
+	return append(append(head, value...), tail...), nil
+}
+
+// encryptBlocks returns the oracle's encryption of blocks, a whole number
+// of blocks, sent as input starting on a block boundary.
+func (t CutAndPaste) encryptBlocks(oracle Encrypter, bs int, blocks []byte) ([]byte, error) {
+	fill := (bs - len(t.Before)%bs) % bs
+	at := len(t.Before) + fill
+	cipher := oracle(append(bytes.Repeat([]byte{t.Filler}, fill), blocks...))
+	if len(cipher) < at+len(blocks) {
+		return nil, ErrUnexpectedLayout
+	}
+	return cipher[at : at+len(blocks)], nil
+}
+
+// splitAfter asks for an encryption in which the first n bytes the oracle
+// adds after the input end on a block boundary, and splits it there.
+func (t CutAndPaste) splitAfter(oracle Encrypter, bs, n int) (head, tail []byte, err error) {
+	fill := (bs - (len(t.Before)+n)%bs) % bs
+	cipher := oracle(bytes.Repeat([]byte{t.Filler}, fill))
+	cut := len(t.Before) + fill + n
+	if len(cipher) < cut {
+		return nil, nil, ErrUnexpectedLayout
+	}
+	return append([]byte{}, cipher[:cut]...), cipher[cut:], nil
+}
@@ -0,0 +1,78 @@
+package set2
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mipearson/matasano"
+)
+
+func TestForgeLastField(t *testing.T) {
+	codec := Codec{'&', '=', QuotePercent}
+	oracle := func(input []byte) []byte {
+		encoded, err := codec.Encode(Fields{{"user", input}, {"lang", []byte("en")}, {"group", []byte("guests-with-a-long-name")}})
+		matasano.CheckErr(err)
+		return PersistentAESECBEncrypt(encoded)
+	}
+
+	for _, value := range []string{"admin", "administrators-of-everything"} {
+		forged, err := ForgeLastField(oracle, CutAndPaste{
+			Before: []byte("user="),
+			After:  []byte("&lang=en&group="),
+			Value:  []byte(value),
+			Filler: 'A',
+		})
+		if err != nil {
+			t.Fatalf("TestForgeLastField(%q): %v", value, err)
+		}
+
+		fields, err := codec.Decode(PersistentAESECBDecrypt(forged))
+		if err != nil {
+			t.Fatalf("TestForgeLastField(%q): %v", value, err)
+		}
+		if got, _ := fields.Get("group"); !bytes.Equal(got, []byte(value)) {
+			t.Errorf("TestForgeLastField(%q): got group %q", value, got)
+		}
+	}
+}
+
+func TestForgeField(t *testing.T) {
+	codec := Codec{'&', '=', QuotePercent}
+	group := []byte("guests-with-a-long-name")
+	oracle := func(input []byte) []byte {
+		encoded, err := codec.Encode(Fields{{"user", input}, {"lang", []byte("en")}, {"group", group}})
+		matasano.CheckErr(err)
+		return PersistentAESECBEncrypt(encoded)
+	}
+
+	cases := []struct {
+		value, expected string
+	}{
+		{"fr", "frAAAAAAAAAAAAAA"},
+		{"sixteen-byte-tag", "sixteen-byte-tag"},
+		{"seventeen-byte-id", "seventeen-byte-idAAAAAAAAAAAAAAA"},
+	}
+	for _, c := range cases {
+		forged, err := ForgeField(oracle, CutAndPaste{
+			Before: []byte("user="),
+			After:  []byte("&lang="),
+			Value:  []byte(c.value),
+			Filler: 'A',
+			Old:    []byte("en"),
+		})
+		if err != nil {
+			t.Fatalf("TestForgeField(%q): %v", c.value, err)
+		}
+
+		fields, err := codec.Decode(PersistentAESECBDecrypt(forged))
+		if err != nil {
+			t.Fatalf("TestForgeField(%q): %v", c.value, err)
+		}
+		if got, _ := fields.Get("lang"); !bytes.Equal(got, []byte(c.expected)) {
+			t.Errorf("TestForgeField(%q): got lang %q expected %q", c.value, got, c.expected)
+		}
+		if got, _ := fields.Get("group"); !bytes.Equal(got, group) {
+			t.Errorf("TestForgeField(%q): got group %q expected %q", c.value, got, group)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package set2
+
+import (
+	"bytes"
+
+	"github.com/mipearson/matasano"
+)
+
+type Mode int
+
+const (
+	ModeECB Mode = iota
+	ModeCBC
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeECB:
+		return "ECB"
+	case ModeCBC:
+		return "CBC"
+	}
+	return "unknown"
+}
+
+// ModeGame is challenge 11: an oracle that secretly picks ECB or CBC under
+// a random key, and surrounds each plaintext with 5-10 random bytes on
+// both sides.
+type ModeGame struct {
+	mode Mode
+	key  []byte
+	iv   []byte
+}
+
+func NewModeGame() *ModeGame {
+	return &ModeGame{
+		mode: Mode(matasano.RandBytes(1)[0] & 1),
+		key:  matasano.RandBytes(16),
+		iv:   matasano.RandBytes(16),
+	}
+}
+
+func (g *ModeGame) Encrypt(plaintext []byte) []byte {
+	plaintext = bytes.Join([][]byte{randomPadding(), plaintext, randomPadding()}, []byte{})
+
+	if g.mode == ModeECB {
+		return matasano.EncryptAESECBPadded(plaintext, g.key, matasano.Pkcs7)
+	}
+	return matasano.EncryptAESCBCPadded(plaintext, g.key, g.iv, matasano.Pkcs7)
+}
+
+func randomPadding() []byte {
+	return matasano.RandBytes(5 + int(matasano.RandBytes(1)[0])%6)
+}
+
+// Verify reports whether guess is the mode the game picked.
+func (g *ModeGame) Verify(guess Mode) bool {
+	return guess == g.mode
+}
+
+// A ModeDetector guesses the mode of an oracle by querying it.
+type ModeDetector func(e Encrypter) Mode
+
+// IsECBDetector guesses with Encrypter.IsECB.
+func IsECBDetector(keysize int) ModeDetector {
+	return func(e Encrypter) Mode {
+		if e.IsECB(keysize) {
+			return ModeECB
+		}
+		return ModeCBC
+	}
+}
+
+type ModeStats struct {
+	Trials     int
+	Correct    int
+	ECBTrials  int
+	ECBCorrect int
+	CBCTrials  int
+	CBCCorrect int
+}
+
+func (s ModeStats) Accuracy() float64 {
+	if s.Trials == 0 {
+		return 0
+	}
+	return float64(s.Correct) / float64(s.Trials)
+}
+
+// PlayModeGame plays trials rounds of ModeGame against detect.
+func PlayModeGame(trials int, detect ModeDetector) ModeStats {
+	stats := ModeStats{}
+	for i := 0; i < trials; i++ {
+		g := NewModeGame()
+		correct := g.Verify(detect(g.Encrypt))
+
+		stats.Trials++
+		if g.mode == ModeECB {
+			stats.ECBTrials++
+		} else {
+			stats.CBCTrials++
+		}
+		if correct {
+			stats.Correct++
+			if g.mode == ModeECB {
+				stats.ECBCorrect++
+			} else {
+				stats.CBCCorrect++
+			}
+		}
+	}
+	return stats
+}
@@ -0,0 +1,38 @@
+package set2
+
+import (
+	"testing"
+)
+
+func TestModeGameEncrypt(t *testing.T) {
+	g := NewModeGame()
+	for i := 0; i < 100; i++ {
+		got := len(g.Encrypt([]byte("hello")))
+		if got != 16 && got != 32 {
+			t.Fatalf("TestModeGameEncrypt: got length %d, expected 16 or 32", got)
+		}
+	}
+}
+
+func TestModeGameVerify(t *testing.T) {
+	g := NewModeGame()
+	if g.Verify(ModeECB) == g.Verify(ModeCBC) {
+		t.Errorf("TestModeGameVerify: exactly one mode should verify")
+	}
+}
+
+func TestPlayModeGame(t *testing.T) {
+	stats := PlayModeGame(2000, IsECBDetector(16))
+	if stats.Accuracy() != 1 {
+		t.Errorf("TestPlayModeGame: got %+v, accuracy %f expected 1", stats, stats.Accuracy())
+	}
+	if stats.ECBTrials == 0 || stats.CBCTrials == 0 || stats.ECBTrials+stats.CBCTrials != 2000 {
+		t.Errorf("TestPlayModeGame: got %+v, expected both modes to be played", stats)
+	}
+
+	guessECB := func(Encrypter) Mode { return ModeECB }
+	stats = PlayModeGame(2000, guessECB)
+	if stats.Correct != stats.ECBTrials || stats.Accuracy() < 0.4 || stats.Accuracy() > 0.6 {
+		t.Errorf("TestPlayModeGame: always guessing ECB got %+v", stats)
+	}
+}
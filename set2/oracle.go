@@ -0,0 +1,240 @@
+package set2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrOracleStatus = errors.New("set2: oracle returned an error status")
+
+// Oracle is an encryption oracle that may be remote, slow or fail.
+type Oracle interface {
+	Query(ctx context.Context, input []byte) ([]byte, error)
+}
+
+type OracleFunc func(ctx context.Context, input []byte) ([]byte, error)
+
+func (f OracleFunc) Query(ctx context.Context, input []byte) ([]byte, error) {
+	return f(ctx, input)
+}
+
+// EncrypterOracle adapts a local Encrypter to the Oracle interface.
+func EncrypterOracle(e Encrypter) Oracle {
+	return OracleFunc(func(ctx context.Context, input []byte) ([]byte, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return e(input), nil
+	})
+}
+
+// oracleFailure carries an Oracle's error out through an Encrypter, which
+// has no error return, to RunWithOracle.
+type oracleFailure struct{ err error }
+
+// RunWithOracle lets attack code written against Encrypter run against o.
+// The Encrypter passed to attack panics if a query fails; RunWithOracle
+// recovers that and returns the query's error.
+func RunWithOracle(ctx context.Context, o Oracle, attack func(e Encrypter)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			f, ok := r.(oracleFailure)
+			if !ok {
+				panic(r)
+			}
+			err = f.err
+		}
+	}()
+
+	attack(oracleEncrypter(ctx, o))
+	return nil
+}
+
+// oracleEncrypter is the Encrypter RunWithOracle hands to attacks. It must
+// only be called under RunWithOracle.
+func oracleEncrypter(ctx context.Context, o Oracle) Encrypter {
+	return func(input []byte) []byte {
+		out, err := o.Query(ctx, input)
+		if err != nil {
+			panic(oracleFailure{err})
+		}
+		return out
+	}
+}
+
+// QueryCounter counts queries passed through CountQueries, and queries
+// answered from memory by CacheQueries. It is safe for concurrent use.
+type QueryCounter struct {
+	queries int64
+	errors  int64
+	hits    int64
+}
+
+func (c *QueryCounter) Queries() int64 { return atomic.LoadInt64(&c.queries) }
+func (c *QueryCounter) Errors() int64  { return atomic.LoadInt64(&c.errors) }
+func (c *QueryCounter) Hits() int64    { return atomic.LoadInt64(&c.hits) }
+
+func CountQueries(o Oracle, c *QueryCounter) Oracle {
+	return OracleFunc(func(ctx context.Context, input []byte) ([]byte, error) {
+		atomic.AddInt64(&c.queries, 1)
+		out, err := o.Query(ctx, input)
+		if err != nil {
+			atomic.AddInt64(&c.errors, 1)
+		}
+		return out, err
+	})
+}
+
+// AddLatency delays every query by d, giving up early if ctx is done.
+func AddLatency(o Oracle, d time.Duration) Oracle {
+	return OracleFunc(func(ctx context.Context, input []byte) ([]byte, error) {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+		return o.Query(ctx, input)
+	})
+}
+
+// RateLimit spaces queries at least interval apart, blocking callers until
+// their turn or until ctx is done.
+func RateLimit(o Oracle, interval time.Duration) Oracle {
+	var mu sync.Mutex
+	var next time.Time
+	return OracleFunc(func(ctx context.Context, input []byte) ([]byte, error) {
+		mu.Lock()
+		now := time.Now()
+		if next.Before(now) {
+			next = now
+		}
+		wait := next.Sub(now)
+		next = next.Add(interval)
+		mu.Unlock()
+
+		if wait > 0 {
+			t := time.NewTimer(wait)
+			defer t.Stop()
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-t.C:
+			}
+		}
+		return o.Query(ctx, input)
+	})
+}
+
+// LogTranscript writes every query and its answer to w as hex, one line
+// each, prefixed "> " and "< ". Failed queries are logged as "! error".
+func LogTranscript(o Oracle, w io.Writer) Oracle {
+	var mu sync.Mutex
+	return OracleFunc(func(ctx context.Context, input []byte) ([]byte, error) {
+		out, err := o.Query(ctx, input)
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(w, "> %x\n", input)
+		if err != nil {
+			fmt.Fprintf(w, "! %v\n", err)
+		} else {
+			fmt.Fprintf(w, "< %x\n", out)
+		}
+		return out, err
+	})
+}
+
+// CacheQueries answers repeated inputs from memory. Failed queries are not
+// cached. c, if not nil, counts the queries answered from the cache in its
+// Hits; its Queries are left to CountQueries.
+func CacheQueries(o Oracle, c *QueryCounter) Oracle {
+	var mu sync.Mutex
+	cache := map[string][]byte{}
+	return OracleFunc(func(ctx context.Context, input []byte) ([]byte, error) {
+		mu.Lock()
+		out, ok := cache[string(input)]
+		mu.Unlock()
+		if ok {
+			if c != nil {
+				atomic.AddInt64(&c.hits, 1)
+			}
+			return out, nil
+		}
+
+		out, err := o.Query(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		cache[string(input)] = out
+		mu.Unlock()
+		return out, nil
+	})
+}
+
+// HTTPOracle queries a remote oracle by POSTing the input as the request
+// body and reading the ciphertext from the response body.
+type HTTPOracle struct {
+	URL    string
+	Client *http.Client
+}
+
+func (h HTTPOracle) Query(ctx context.Context, input []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(input))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrOracleStatus, resp.Status)
+	}
+	return body, nil
+}
+
+// OracleHandler serves o over HTTP in the form HTTPOracle expects.
+func OracleHandler(o Oracle) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "POST only", http.StatusMethodNotAllowed)
+			return
+		}
+		input, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		out, err := o.Query(r.Context(), input)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		if _, err := w.Write(out); err != nil {
+			log.Printf("set2: writing oracle response: %v", err)
+		}
+	})
+}
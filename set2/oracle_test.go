@@ -0,0 +1,95 @@
+package set2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunWithOracleOverHTTP(t *testing.T) {
+	server := httptest.NewServer(OracleHandler(EncrypterOracle(Set2Challenge12Crypt)))
+	defer server.Close()
+
+	counter := &QueryCounter{}
+	o := CountQueries(HTTPOracle{URL: server.URL, Client: server.Client()}, counter)
+
+	var keysize int
+	var isECB bool
+	var first []byte
+	err := RunWithOracle(context.Background(), o, func(e Encrypter) {
+		keysize = e.DiscoverKeysize()
+		isECB = e.IsECB(keysize)
+		for len(first) < 5 {
+			first = append(first, e.DiscoverNextByte(keysize, first))
+		}
+	})
+	if err != nil {
+		t.Fatalf("TestRunWithOracleOverHTTP: %v", err)
+	}
+	if keysize != 16 || !isECB || string(first) != "Rolli" {
+		t.Errorf("TestRunWithOracleOverHTTP: got keysize %d, ECB %v, %q", keysize, isECB, first)
+	}
+	if counter.Queries() == 0 || counter.Errors() != 0 {
+		t.Errorf("TestRunWithOracleOverHTTP: got %d queries, %d errors", counter.Queries(), counter.Errors())
+	}
+}
+
+func TestRunWithOracleReturnsQueryError(t *testing.T) {
+	failing := OracleFunc(func(ctx context.Context, input []byte) ([]byte, error) {
+		return nil, errors.New("boom")
+	})
+	server := httptest.NewServer(OracleHandler(failing))
+	defer server.Close()
+
+	err := RunWithOracle(context.Background(), HTTPOracle{URL: server.URL}, func(e Encrypter) {
+		e.DiscoverKeysize()
+		t.Errorf("TestRunWithOracleReturnsQueryError: attack kept running after a failed query")
+	})
+	if !errors.Is(err, ErrOracleStatus) {
+		t.Errorf("TestRunWithOracleReturnsQueryError: got %v expected %v", err, ErrOracleStatus)
+	}
+}
+
+func TestCacheQueries(t *testing.T) {
+	counter := &QueryCounter{}
+	o := CacheQueries(CountQueries(EncrypterOracle(RandomECB), counter), counter)
+	a, _ := o.Query(context.Background(), []byte("a"))
+	b, _ := o.Query(context.Background(), []byte("a"))
+	if !bytes.Equal(a, b) || counter.Queries() != 1 || counter.Hits() != 1 {
+		t.Errorf("TestCacheQueries: got %d queries, %d hits", counter.Queries(), counter.Hits())
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	o := RateLimit(EncrypterOracle(RandomECB), 10*time.Millisecond)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		o.Query(context.Background(), []byte("a"))
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("TestRateLimit: 4 queries took %v, expected at least 30ms", elapsed)
+	}
+}
+
+func TestAddLatencyHonoursContext(t *testing.T) {
+	o := AddLatency(EncrypterOracle(RandomECB), time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := o.Query(ctx, []byte("a")); err != context.DeadlineExceeded {
+		t.Errorf("TestAddLatencyHonoursContext: got %v expected %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestLogTranscript(t *testing.T) {
+	var log strings.Builder
+	o := LogTranscript(EncrypterOracle(RandomECB), &log)
+	o.Query(context.Background(), []byte("hi"))
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	if len(lines) != 2 || lines[0] != "> 6869" || !strings.HasPrefix(lines[1], "< ") || len(lines[1]) != 2+32 {
+		t.Errorf("TestLogTranscript: got %q", log.String())
+	}
+}
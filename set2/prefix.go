@@ -0,0 +1,240 @@
+package set2
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/mipearson/matasano"
+)
+
+var (
+	ErrNotECB         = errors.New("set2: oracle does not encrypt with ECB")
+	ErrMarkerNotFound = errors.New("set2: could not line up marker blocks in oracle output")
+	ErrNoBlockSize    = errors.New("set2: could not discover block size")
+)
+
+const (
+	maxAlignmentProbes = 1024
+	fixedPrefixChecks  = 3
+)
+
+// PrefixedEncrypter prepends prefix() to every plaintext before handing it
+// to e, modelling an oracle that adds attacker-unknown bytes in front of the
+// attacker's input.
+func PrefixedEncrypter(e Encrypter, prefix func() []byte) Encrypter {
+	return func(plaintext []byte) []byte {
+		return e(bytes.Join([][]byte{prefix(), plaintext}, []byte{}))
+	}
+}
+
+// RandomPrefix returns between 0 and max-1 random bytes, or none if max is
+// not positive.
+func RandomPrefix(max int) []byte {
+	if max <= 0 {
+		return []byte{}
+	}
+	n := int(matasano.RandBytes(1)[0]) % max
+	return matasano.RandBytes(n)
+}
+
+var CachedPersistentPrefix []byte
+
+func PersistentPrefix() []byte {
+	if CachedPersistentPrefix == nil {
+		CachedPersistentPrefix = RandomPrefix(64)
+	}
+	return CachedPersistentPrefix
+}
+
+func Set2Challenge14Crypt(plaintext []byte) []byte {
+	return PrefixedEncrypter(Set2Challenge12Crypt, PersistentPrefix)(plaintext)
+}
+
+// DiscoverBlocksize takes the greatest common divisor of the output lengths
+// for a range of input lengths. Unlike DiscoverKeysize it copes with oracles
+// whose output length varies from call to call, such as ones that add a
+// random-length prefix every time.
+func (e Encrypter) DiscoverBlocksize() (int, error) {
+	size := 0
+	for n := 0; n < 64; n++ {
+		size = gcd(size, len(e(bytes.Repeat([]byte("A"), n))))
+	}
+	if size < 2 {
+		return 0, ErrNoBlockSize
+	}
+	return size, nil
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// DiscoverPrefixLength finds how many bytes a fixed-prefix oracle puts in
+// front of the input, by sliding two identical marker blocks along until
+// they encrypt to two identical consecutive blocks.
+func (e Encrypter) DiscoverPrefixLength(blocksize int) (int, error) {
+	marker := markerBlocks(blocksize)
+	encryptedMarker, err := e.encryptMarker(blocksize, marker)
+	if err != nil {
+		return 0, err
+	}
+	for fill := 1; fill <= blocksize; fill++ {
+		if at := repeatedPair(e(markerInput(marker, fill, nil)), blocksize, encryptedMarker); at != -1 {
+			return at - fill, nil
+		}
+	}
+	return 0, ErrMarkerNotFound
+}
+
+// markerBlocks is two copies of a random block, unlikely to match anything
+// else the oracle encrypts.
+func markerBlocks(blocksize int) []byte {
+	block := matasano.RandBytes(blocksize)
+	return append(block, block...)
+}
+
+// markerInput puts fill bytes in front of marker and a guard block after
+// it, followed by plaintext. A marker that is off a block boundary by s
+// bytes still encrypts to a repeated pair if the s bytes next to it match
+// its own ends, so the fill bytes differ from the marker's last byte and
+// the guard's from its first. fill must be at least one.
+func markerInput(marker []byte, fill int, plaintext []byte) []byte {
+	return bytes.Join([][]byte{
+		bytes.Repeat([]byte{^marker[len(marker)-1]}, fill),
+		marker,
+		bytes.Repeat([]byte{^marker[0]}, len(marker)/2),
+		plaintext,
+	}, []byte{})
+}
+
+// encryptMarker learns what the oracle encrypts a marker block to. A
+// repeated pair of blocks only counts if it disappears when a different
+// marker is sent, so repeats inside the prefix itself are ignored.
+func (e Encrypter) encryptMarker(blocksize int, marker []byte) ([]byte, error) {
+	other := markerBlocks(blocksize)
+	for probe := 0; probe < maxAlignmentProbes; probe++ {
+		fill := probe%blocksize + 1
+		cipher := e(markerInput(marker, fill, nil))
+		control := e(markerInput(other, fill, nil))
+		for i := 0; i+2*blocksize <= len(cipher); i += blocksize {
+			block := cipher[i : i+blocksize]
+			if bytes.Equal(block, cipher[i+blocksize:i+2*blocksize]) && !containsBlock(control, block) {
+				return append([]byte{}, block...), nil
+			}
+		}
+	}
+	return nil, ErrMarkerNotFound
+}
+
+func containsBlock(cipher, block []byte) bool {
+	for i := 0; i+len(block) <= len(cipher); i += len(block) {
+		if bytes.Equal(cipher[i:i+len(block)], block) {
+			return true
+		}
+	}
+	return false
+}
+
+// repeatedPair returns the byte offset of the first pair of consecutive
+// blocks in cipher that both equal want, or -1.
+func repeatedPair(cipher []byte, blocksize int, want []byte) int {
+	for i := 0; i+2*blocksize <= len(cipher); i += blocksize {
+		if bytes.Equal(cipher[i:i+blocksize], want) && bytes.Equal(cipher[i+blocksize:i+2*blocksize], want) {
+			return i
+		}
+	}
+	return -1
+}
+
+// fixedPrefixLength reports how long the prefix is if the oracle adds the
+// same one to every query. An oracle that encrypts the same input
+// differently from call to call is taken to add a fresh prefix each time.
+func (e Encrypter) fixedPrefixLength(blocksize int) (int, bool) {
+	input := markerBlocks(blocksize)
+	first := e(input)
+	for i := 0; i < fixedPrefixChecks; i++ {
+		if !bytes.Equal(e(input), first) {
+			return 0, false
+		}
+	}
+	n, err := e.DiscoverPrefixLength(blocksize)
+	return n, err == nil
+}
+
+// strippedEncrypter turns an ECB oracle that always adds a prefix of
+// prefixLen bytes into one whose output starts at the attacker's input, by
+// padding the prefix out to a block boundary and cutting it off.
+func (e Encrypter) strippedEncrypter(blocksize, prefixLen int) Encrypter {
+	fill := (blocksize - prefixLen%blocksize) % blocksize
+	return func(plaintext []byte) []byte {
+		cipher := e(append(bytes.Repeat([]byte{0}, fill), plaintext...))
+		return cipher[prefixLen+fill:]
+	}
+}
+
+// alignedEncrypter turns an ECB oracle that adds a prefix, fixed or
+// different on each call, into one whose output starts at the attacker's
+// input. Each query is sent after some fill bytes, two marker blocks and
+// a guard block; the fill length is varied until the markers come back as
+// two identical blocks, and everything up to and including the guard is
+// cut off. If the markers cannot be lined up it fails the query with
+// ErrMarkerNotFound, so it must be used under RunWithOracle.
+func (e Encrypter) alignedEncrypter(blocksize int) Encrypter {
+	marker := markerBlocks(blocksize)
+	var encryptedMarker []byte
+	fill := 1
+
+	return func(plaintext []byte) []byte {
+		if encryptedMarker == nil {
+			var err error
+			if encryptedMarker, err = e.encryptMarker(blocksize, marker); err != nil {
+				panic(oracleFailure{ErrMarkerNotFound})
+			}
+		}
+		for probe := 0; probe < maxAlignmentProbes; probe++ {
+			cipher := e(markerInput(marker, fill, plaintext))
+			if at := repeatedPair(cipher, blocksize, encryptedMarker); at != -1 {
+				return cipher[at+3*blocksize:]
+			}
+			fill = fill%blocksize + 1
+		}
+		panic(oracleFailure{ErrMarkerNotFound})
+	}
+}
+
+// DecryptECBSuffix recovers the secret an ECB oracle appends to the
+// attacker's input, one byte at a time, whether or not the oracle also
+// adds a prefix. A prefix that is the same on every call is measured once
+// with DiscoverPrefixLength; one that changes is lined up on every query
+// with marker blocks.
+func DecryptECBSuffix(e Encrypter) ([]byte, error) {
+	secret, _, err := DecryptECBSuffixWith(e, ByteAtATimeOptions{})
+	return secret, err
+}
+
+// decryptSuffix runs the byte-at-a-time attack against an oracle that puts
+// the attacker's input first.
+func (e Encrypter) decryptSuffix(keysize int) []byte {
+	return recoverSuffix(keysize, e.discoverNextByte)
+}
+
+func recoverSuffix(keysize int, next func(int, []byte) (byte, bool)) []byte {
+	known := []byte{}
+	for {
+		b, ok := next(keysize, known)
+		if !ok {
+			break
+		}
+		known = append(known, b)
+	}
+
+	// The last byte recovered is always the single \x01 of padding: the
+	// byte after it no longer lines up with the padding the oracle adds.
+	if len(known) > 0 && known[len(known)-1] == 1 {
+		known = known[:len(known)-1]
+	}
+	return known
+}
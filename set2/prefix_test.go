@@ -0,0 +1,109 @@
+package set2
+
+import (
+	"bytes"
+	"testing"
+)
+
+var challenge12Suffix = []byte("Rollin' in my 5.0\nWith my rag-top down so my hair can blow\nThe girlies on standby waving just to say hi\nDid you stop? No, I just drove by\n")
+
+func TestDiscoverBlocksize(t *testing.T) {
+	e := PrefixedEncrypter(Set2Challenge12Crypt, func() []byte { return RandomPrefix(40) })
+	got, err := e.DiscoverBlocksize()
+	if err != nil || got != 16 {
+		t.Errorf("TestDiscoverBlocksize: got %d, %v expected 16", got, err)
+	}
+}
+
+func TestDiscoverPrefixLength(t *testing.T) {
+	for _, n := range []int{0, 1, 15, 16, 17, 37} {
+		prefix := bytes.Repeat([]byte("P"), n)
+		e := PrefixedEncrypter(Set2Challenge12Crypt, func() []byte { return prefix })
+		got, err := e.DiscoverPrefixLength(16)
+		if err != nil || got != n {
+			t.Errorf("TestDiscoverPrefixLength(%d): got %d, %v", n, got, err)
+		}
+	}
+}
+
+func TestEncryptMarkerOffBoundary(t *testing.T) {
+	// The marker starts with the secret's first byte and ends with a zero,
+	// so a marker one byte off a block boundary would look lined up if it
+	// were sent between zeros and the secret.
+	block := append(append([]byte{challenge12Suffix[0]}, bytes.Repeat([]byte("M"), 14)...), 0)
+	marker := append(append([]byte{}, block...), block...)
+	e := PrefixedEncrypter(Set2Challenge12Crypt, func() []byte { return RandomPrefix(40) })
+	want := Set2Challenge12Crypt(block)[:16]
+	for i := 0; i < 50; i++ {
+		got, err := e.encryptMarker(16, marker)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("TestEncryptMarkerOffBoundary: got %x, %v expected %x", got, err, want)
+		}
+	}
+}
+
+func TestSet2Challenge14Decrypt(t *testing.T) {
+	got, err := DecryptECBSuffix(Set2Challenge14Crypt)
+	if err != nil {
+		t.Fatalf("TestSet2Challenge14Decrypt: %v", err)
+	}
+	if !bytes.Equal(got, challenge12Suffix) {
+		t.Errorf("TestSet2Challenge14Decrypt: got %q expected %q", got, challenge12Suffix)
+	}
+}
+
+func TestDecryptECBSuffixRandomPrefixPerCall(t *testing.T) {
+	e := PrefixedEncrypter(Set2Challenge12Crypt, func() []byte { return RandomPrefix(40) })
+	got, err := DecryptECBSuffix(e)
+	if err != nil {
+		t.Fatalf("TestDecryptECBSuffixRandomPrefixPerCall: %v", err)
+	}
+	if !bytes.Equal(got, challenge12Suffix) {
+		t.Errorf("TestDecryptECBSuffixRandomPrefixPerCall: got %q expected %q", got, challenge12Suffix)
+	}
+}
+
+func TestDecryptECBSuffixFixedPrefix(t *testing.T) {
+	for _, n := range []int{0, 5, 16, 37} {
+		prefix := bytes.Repeat([]byte("P"), n)
+		e := PrefixedEncrypter(Set2Challenge12Crypt, func() []byte { return prefix })
+		got, stats, err := DecryptECBSuffixWith(e, ByteAtATimeOptions{Batch: true})
+		if err != nil || !bytes.Equal(got, challenge12Suffix) {
+			t.Errorf("TestDecryptECBSuffixFixedPrefix(%d): got %q, %v expected %q", n, got, err, challenge12Suffix)
+		}
+
+		// A fixed prefix is measured once rather than lined up with
+		// marker blocks on every query.
+		limit := len(challenge12Suffix) + 2 + 64 + 1 + 1 + fixedPrefixChecks + 2*16 + 16
+		if stats.Queries > limit {
+			t.Errorf("TestDecryptECBSuffixFixedPrefix(%d): got %d queries, expected at most %d", n, stats.Queries, limit)
+		}
+	}
+}
+
+func TestRandomPrefix(t *testing.T) {
+	for _, max := range []int{-1, 0, 1} {
+		if got := RandomPrefix(max); len(got) != 0 {
+			t.Errorf("TestRandomPrefix(%d): got %q expected no bytes", max, got)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if got := RandomPrefix(5); len(got) >= 5 {
+			t.Errorf("TestRandomPrefix(5): got %d bytes", len(got))
+		}
+	}
+}
+
+func TestDecryptECBSuffixRejectsCBC(t *testing.T) {
+	if _, err := DecryptECBSuffix(RandomCBC); err != ErrNotECB {
+		t.Errorf("TestDecryptECBSuffixRejectsCBC: got %v expected %v", err, ErrNotECB)
+	}
+}
+
+func TestDecryptECBSuffixMarkerNotFound(t *testing.T) {
+	// A fresh key on every call means the markers never encrypt the same
+	// way twice.
+	if _, err := DecryptECBSuffix(RandomECB); err != ErrMarkerNotFound {
+		t.Errorf("TestDecryptECBSuffixMarkerNotFound: got %v expected %v", err, ErrMarkerNotFound)
+	}
+}
@@ -0,0 +1,156 @@
+package set2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/mipearson/matasano"
+)
+
+var ErrProbeLimit = errors.New("set2: oracle output length did not change within the probe limit")
+
+const DefaultMaxProbes = 256
+
+// OracleProfile is what Probe learns about an oracle from the lengths of
+// its output, and for ECB from one look at its last block.
+type OracleProfile struct {
+	// BlockSize is 1 for stream ciphers.
+	BlockSize int
+	// SecretLength is how many bytes the oracle adds to the input. It is
+	// exact for ECB and for oracles that only accept whole blocks; for
+	// other block modes padding cannot be seen, and it assumes padding is
+	// always added, as PKCS#7 does. For a stream cipher it includes any IV
+	// or nonce sent with the output.
+	SecretLength int
+	// Padded is set when the oracle was seen to add a whole block of
+	// padding to input that already filled its last block, as PKCS#7 does
+	// and zero padding does not.
+	Padded bool
+	// Stream is set when the output grows one byte per input byte.
+	Stream bool
+	// Probes is the number of oracle calls made.
+	Probes int
+}
+
+// Probe grows the input one byte at a time until the output length
+// changes, giving up with ErrProbeLimit after maxProbes calls. For a block
+// cipher it then spends one more call on addsPaddingBlock. An oracle that
+// only accepts whole blocks has to report that as an error, so it must be
+// probed with ProbeOracle instead.
+func (e Encrypter) Probe(maxProbes int) (OracleProfile, error) {
+	return ProbeOracle(context.Background(), EncrypterOracle(e), maxProbes)
+}
+
+// ProbeOracle is Probe for an Oracle. A query that fails with
+// matasano.ErrNotBlockMultiple is taken to mean the oracle has no padding,
+// and the oracle is measured from the lengths it accepts instead. Any
+// other failed query ends the probe with its error.
+func ProbeOracle(ctx context.Context, o Oracle, maxProbes int) (OracleProfile, error) {
+	profile := OracleProfile{}
+	if maxProbes < 2 {
+		return profile, ErrProbeLimit
+	}
+
+	base, ok, err := probeLength(ctx, o, 0)
+	profile.Probes++
+	if err != nil {
+		return profile, err
+	}
+	first, rejected := 0, !ok
+	if rejected {
+		first = -1
+	}
+	for n := 1; profile.Probes < maxProbes; n++ {
+		size, ok, err := probeLength(ctx, o, n)
+		profile.Probes++
+		if err != nil {
+			return profile, err
+		}
+		switch {
+		case !ok:
+			rejected = true
+			continue
+		case rejected && first == -1:
+			first, base = n, size
+			continue
+		case rejected:
+			profile.BlockSize = n - first
+			profile.SecretLength = base - first
+			return profile, nil
+		case size == base:
+			continue
+		}
+
+		if n == 1 && size == base+1 {
+			profile.BlockSize = 1
+			profile.SecretLength = base
+			profile.Stream = true
+			return profile, nil
+		}
+
+		profile.BlockSize = size - base
+		if profile.Probes == maxProbes {
+			return profile, ErrProbeLimit
+		}
+		padded, ecb, err := addsPaddingBlock(ctx, o, n, profile.BlockSize)
+		profile.Probes++
+		if err != nil {
+			return profile, err
+		}
+		profile.Padded = padded
+		profile.SecretLength = base - n
+		if ecb && !padded {
+			// The output grew because the secret spilled a byte into a
+			// new block, not because a block of padding was added.
+			profile.SecretLength++
+		}
+		return profile, nil
+	}
+	return profile, ErrProbeLimit
+}
+
+// probeLength returns the length of the oracle's output for n bytes of
+// input, or false if the oracle rejected the input for not filling whole
+// blocks.
+func probeLength(ctx context.Context, o Oracle, n int) (int, bool, error) {
+	out, err := o.Query(ctx, bytes.Repeat([]byte("A"), n))
+	if errors.Is(err, matasano.ErrNotBlockMultiple) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return len(out), true, nil
+}
+
+// fullPaddingSchemes are the schemes whose padding block addsPaddingBlock
+// looks for. ISO 10126 pads with random bytes and cannot be recognised.
+var fullPaddingSchemes = []matasano.Padding{matasano.Pkcs7, matasano.AnsiX923, matasano.Iso7816}
+
+// addsPaddingBlock reports whether the oracle's output for n bytes of
+// input, n being where the output grew, ends in a block of nothing but
+// padding. Each scheme's padding block is sent blocksize times, a byte
+// further along each time so that one copy lines up whatever the oracle
+// puts in front of the input, and the last output block is looked for
+// among them. A zero-padded secret ending in 0x10 or 0x80 looks the same.
+// ecb is false if the output has no repeated blocks, in which case nothing
+// can be told.
+func addsPaddingBlock(ctx context.Context, o Oracle, n, blocksize int) (padded, ecb bool, err error) {
+	var input []byte
+	for _, p := range fullPaddingSchemes {
+		block := p.Pad(nil, blocksize)
+		for i := 0; i < blocksize; i++ {
+			input = append(append(input, block...), 0x10)
+		}
+	}
+	cipher, err := o.Query(ctx, append(input, bytes.Repeat([]byte("A"), n)...))
+	if err != nil {
+		return false, false, err
+	}
+	if !matasano.CipherIsECB(cipher, blocksize) {
+		return false, false, nil
+	}
+	last := cipher[len(cipher)-blocksize:]
+	return containsBlock(cipher[:len(cipher)-blocksize], last), true, nil
+}
@@ -0,0 +1,114 @@
+package set2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mipearson/matasano"
+)
+
+func ctrOracle(secret []byte) Encrypter {
+	key, nonce := matasano.RandBytes(16), matasano.RandBytes(8)
+	return func(plaintext []byte) []byte {
+		return matasano.EncryptAESCTR(append(plaintext, secret...), key, nonce, matasano.CTRLittleEndian64)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	for _, n := range []int{0, 1, 5, 15, 16, 17, 138} {
+		secret := bytes.Repeat([]byte("s"), n)
+		e := Encrypter(func(plaintext []byte) []byte { return PersistentAESECBEncrypt(append(plaintext, secret...)) })
+
+		got, err := e.Probe(DefaultMaxProbes)
+		if err != nil || got.BlockSize != 16 || got.SecretLength != n || !got.Padded || got.Stream {
+			t.Errorf("TestProbe(%d): got %+v, %v", n, got, err)
+		}
+	}
+}
+
+func TestProbePaddings(t *testing.T) {
+	cases := []struct {
+		padding matasano.Padding
+		padded  bool
+	}{
+		{matasano.AnsiX923, true},
+		{matasano.Iso7816, true},
+		{matasano.ZeroPadding, false},
+		{matasano.NoPadding, false},
+	}
+	for _, c := range cases {
+		for _, n := range []int{0, 5, 16, 20} {
+			secret := bytes.Repeat([]byte("s"), n)
+			o := OracleFunc(func(ctx context.Context, plaintext []byte) ([]byte, error) {
+				return matasano.TryEncryptAESECBPadded(append(plaintext, secret...), PersistentKey(), c.padding)
+			})
+
+			got, err := ProbeOracle(context.Background(), o, DefaultMaxProbes)
+			if err != nil || got.BlockSize != 16 || got.SecretLength != n || got.Padded != c.padded || got.Stream {
+				t.Errorf("TestProbePaddings(%v, %d): got %+v, %v", c.padding, n, got, err)
+			}
+		}
+	}
+}
+
+func TestProbeOracleError(t *testing.T) {
+	errDown := errors.New("oracle is down")
+	calls := 0
+	o := OracleFunc(func(ctx context.Context, plaintext []byte) ([]byte, error) {
+		calls++
+		if calls == 3 {
+			return nil, errDown
+		}
+		return make([]byte, 16), nil
+	})
+	got, err := ProbeOracle(context.Background(), o, DefaultMaxProbes)
+	if err != errDown || got.Probes != 3 {
+		t.Errorf("TestProbeOracleError: got %+v, %v expected %v", got, err, errDown)
+	}
+}
+
+func TestProbePrefix(t *testing.T) {
+	got, err := Encrypter(Set2Challenge14Crypt).Probe(DefaultMaxProbes)
+	expected := len(PersistentPrefix()) + len(challenge12Suffix)
+	if err != nil || got.SecretLength != expected || !got.Padded {
+		t.Errorf("TestProbePrefix: got %+v, %v expected secret length %d", got, err, expected)
+	}
+}
+
+func TestProbeCBC(t *testing.T) {
+	got, err := Encrypter(RandomCBC).Probe(DefaultMaxProbes)
+	if err != nil || got.BlockSize != 16 || got.SecretLength != 0 || got.Padded {
+		t.Errorf("TestProbeCBC: got %+v, %v", got, err)
+	}
+}
+
+func TestProbeChallenge12(t *testing.T) {
+	got, err := Encrypter(Set2Challenge12Crypt).Probe(DefaultMaxProbes)
+	if err != nil || got.SecretLength != len(challenge12Suffix) || !got.Padded {
+		t.Errorf("TestProbeChallenge12: got %+v, %v expected secret length %d", got, err, len(challenge12Suffix))
+	}
+}
+
+func TestProbeStream(t *testing.T) {
+	got, err := ctrOracle([]byte("secret")).Probe(DefaultMaxProbes)
+	expected := OracleProfile{BlockSize: 1, SecretLength: 6, Stream: true, Probes: 2}
+	if err != nil || got != expected {
+		t.Errorf("TestProbeStream: got %+v, %v expected %+v", got, err, expected)
+	}
+	if got := ctrOracle(nil).DiscoverKeysize(); got != 1 {
+		t.Errorf("TestProbeStream: DiscoverKeysize got %d expected 1", got)
+	}
+}
+
+func TestProbeLimit(t *testing.T) {
+	constant := Encrypter(func([]byte) []byte { return make([]byte, 32) })
+	got, err := constant.Probe(10)
+	if err != ErrProbeLimit || got.Probes != 10 {
+		t.Errorf("TestProbeLimit: got %+v, %v expected %v", got, err, ErrProbeLimit)
+	}
+	if got := constant.DiscoverKeysize(); got != 0 {
+		t.Errorf("TestProbeLimit: DiscoverKeysize got %d expected 0", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"sort"
 
 	"github.com/mipearson/matasano"
 )
@@ -11,27 +12,38 @@ import (
 type Encrypter func([]byte) []byte
 
 func RandomECB(plaintext []byte) []byte {
-	return matasano.EncryptAESECB(matasano.Pkcs7Padding(plaintext, 16), matasano.RandBytes(16))
+	return RandomECBWithPadding(matasano.Pkcs7)(plaintext)
 }
 
 func RandomCBC(plaintext []byte) []byte {
-	return matasano.EncryptAESCBC(matasano.Pkcs7Padding(plaintext, 16), matasano.RandBytes(16), matasano.RandBytes(16))
+	return RandomCBCWithPadding(matasano.Pkcs7)(plaintext)
 }
 
-func (e Encrypter) DiscoverKeysize() int {
-	plaintext := []byte("A")
-	base := len(e(plaintext))
-	newlen := base
-	for ; newlen == base; plaintext = append(plaintext, 'A') {
-		newlen = len(e(plaintext))
+func RandomECBWithPadding(padding matasano.Padding) Encrypter {
+	return func(plaintext []byte) []byte {
+		return matasano.EncryptAESECBPadded(plaintext, matasano.RandBytes(16), padding)
 	}
+}
 
-	return newlen - base
+func RandomCBCWithPadding(padding matasano.Padding) Encrypter {
+	return func(plaintext []byte) []byte {
+		return matasano.EncryptAESCBCPadded(plaintext, matasano.RandBytes(16), matasano.RandBytes(16), padding)
+	}
+}
+
+// DiscoverKeysize returns the oracle's block size, 1 for a stream cipher,
+// or 0 if its output length never changes.
+func (e Encrypter) DiscoverKeysize() int {
+	profile, err := e.Probe(DefaultMaxProbes)
+	if err != nil {
+		return 0
+	}
+	return profile.BlockSize
 }
 
 func (e Encrypter) IsECB(keysize int) bool {
 	plaintext := bytes.Repeat([]byte(" "), keysize*4)
-	return matasano.CipherIsECB(e(plaintext), 16)
+	return matasano.CipherIsECB(e(plaintext), keysize)
 }
 
 var CachedPersistentKey []byte
@@ -45,11 +57,17 @@ func PersistentKey() []byte {
 }
 
 func PersistentAESECBEncrypt(plaintext []byte) []byte {
-	return matasano.EncryptAESECB(matasano.Pkcs7Padding(plaintext, len(PersistentKey())), PersistentKey())
+	return PersistentAESECBEncrypter(matasano.Pkcs7)(plaintext)
+}
+
+func PersistentAESECBEncrypter(padding matasano.Padding) Encrypter {
+	return func(plaintext []byte) []byte {
+		return matasano.EncryptAESECBPadded(plaintext, PersistentKey(), padding)
+	}
 }
 
 func PersistentAESECBDecrypt(ciphertext []byte) []byte {
-	return matasano.StripPadding(matasano.DecryptAESECB(ciphertext, PersistentKey()))
+	return matasano.StripPadding(matasano.DecryptAESECB(ciphertext, PersistentKey()), len(PersistentKey()))
 }
 
 func Set2Challenge12Crypt(plaintext []byte) []byte {
@@ -67,13 +85,7 @@ func Set2Challenge12Decrypt() []byte {
 		log.Fatal("Expected Set2Challenge12Crypt to encrypt as ECB, but it didn't!")
 	}
 
-	cipherLen := len(e([]byte("")))
-	known := []byte{}
-	for len(known) < cipherLen {
-		known = append(known, e.DiscoverNextByte(keysize, known))
-	}
-
-	return known
+	return e.decryptSuffix(keysize)
 }
 
 func discoveryPrefix(keysize int, known []byte) (prefix []byte, candidate []byte) {
@@ -91,38 +103,77 @@ func discoveryPrefix(keysize int, known []byte) (prefix []byte, candidate []byte
 }
 
 func (e Encrypter) DiscoverNextByte(keysize int, known []byte) byte {
+	next, ok := e.discoverNextByte(keysize, known)
+	if !ok {
+		return ' '
+	}
+	return next
+}
 
+func (e Encrypter) discoverNextByte(keysize int, known []byte) (byte, bool) {
 	prefix, candidate := discoveryPrefix(keysize, known)
 
 	blockAt := len(known) - (len(known) % keysize)
 	base := e(prefix)[blockAt : blockAt+keysize]
 
 	var i int
+	var cipher []byte
 	candidate = append(candidate, ' ')
-	for cipher := []byte{}; i < 256 && !bytes.Equal(cipher, base); i++ {
+	for ; i < 256 && !bytes.Equal(cipher, base); i++ {
 		candidate[len(candidate)-1] = byte(i)
 		cipher = e(candidate)[:keysize]
 	}
-	if i == 256 {
-		return ' '
-	} else {
-		return byte(i - 1)
+	if !bytes.Equal(cipher, base) {
+		return 0, false
 	}
+	return byte(i - 1), true
 }
 
 type Profile map[string][]byte
 
+var profileFieldOrder = []string{"email", "uid", "role"}
+
+// Fields lists p's fields with email, uid and role first, then the rest
+// sorted by key, so that encoding is deterministic.
+func (p Profile) Fields() Fields {
+	keys := make([]string, 0, len(p))
+	for _, k := range profileFieldOrder {
+		if _, ok := p[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	rest := make([]string, 0)
+	for k := range p {
+		if !containsString(profileFieldOrder, k) {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	keys = append(keys, rest...)
+
+	fields := make(Fields, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, Field{k, p[k]})
+	}
+	return fields
+}
+
 func (p Profile) AsBytes() []byte {
-	parts := make([][]byte, 0)
-	for k, v := range p {
-		parts = append(parts, []byte(fmt.Sprintf("%s=%s", k, v)))
+	encoded, err := CookieCodec.Encode(p.Fields())
+	matasano.CheckErr(err)
+	return encoded
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
 	}
-	return bytes.Join(parts, []byte("&"))
+	return false
 }
 
 func ProfileFor(email []byte) []byte {
-	email = bytes.Replace(email, []byte("&"), []byte{}, -1)
-	email = bytes.Replace(email, []byte("="), []byte{}, -1)
 	return PersistentAESECBEncrypt(Profile{
 		"email": email,
 		"uid":   []byte("10"),
@@ -139,30 +190,43 @@ func (p Profile) IsAdmin() bool {
 	return false
 }
 
+// ParseProfile decodes src strictly, rejecting malformed segments and
+// repeated keys.
+func ParseProfile(src []byte) (Profile, error) {
+	fields, err := CookieCodec.Decode(src)
+	if err != nil {
+		return nil, err
+	}
+	profile := Profile{}
+	for _, f := range fields {
+		if _, ok := profile[f.Key]; ok {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateField, f.Key)
+		}
+		profile[f.Key] = f.Value
+	}
+	return profile, nil
+}
+
+// BytesToProfile decodes src leniently: segments without '=' are skipped
+// and later keys override earlier ones.
 func BytesToProfile(src []byte) Profile {
-	parts := bytes.Split(src, []byte("&"))
 	profile := Profile{}
-	for _, p := range parts {
+	for _, p := range bytes.Split(src, []byte("&")) {
 		kv := bytes.SplitN(p, []byte("="), 2)
-		profile[string(kv[0])] = kv[1]
+		if len(kv) == 2 {
+			profile[string(kv[0])] = kv[1]
+		}
 	}
 	return profile
 }
 
 func Set2Challenge13ForceAdminProfile() []byte {
-	keysize := Encrypter(ProfileFor).DiscoverKeysize()
-	offset := len("email=")
-	target := []byte("admin")
-
-	prefix := bytes.Repeat([]byte(" "), keysize-offset)
-	suffix := bytes.Repeat([]byte{4}, keysize-len(target))
-
-	profile := ProfileFor(bytes.Join([][]byte{prefix, target, suffix}, []byte{}))
-
-	adminCipher := profile[keysize : keysize*2]
-
-	paddingRequired := keysize - (len("user=&uid=10&role=") % keysize) - 1
-	profile = ProfileFor(bytes.Repeat([]byte(" "), paddingRequired))
-	copy(profile[len(profile)-keysize:], adminCipher)
-	return profile
+	forged, err := ForgeLastField(ProfileFor, CutAndPaste{
+		Before: []byte("email="),
+		After:  []byte("&uid=10&role="),
+		Value:  []byte("admin"),
+		Filler: ' ',
+	})
+	matasano.CheckErr(err)
+	return forged
 }
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"reflect"
 	"testing"
+
+	"github.com/mipearson/matasano"
 )
 
 func TestDiscoverKeysize(t *testing.T) {
@@ -22,6 +24,17 @@ func TestIsECB(t *testing.T) {
 	}
 }
 
+func TestIsECBWithPadding(t *testing.T) {
+	for _, p := range []matasano.Padding{matasano.Pkcs7, matasano.AnsiX923, matasano.Iso7816, matasano.Iso10126, matasano.ZeroPadding} {
+		if !RandomECBWithPadding(p).IsECB(16) {
+			t.Errorf("IsECB on RandomECBWithPadding(%v) got false, expected true", p)
+		}
+		if RandomCBCWithPadding(p).IsECB(16) {
+			t.Errorf("IsECB on RandomCBCWithPadding(%v) got true, expected false", p)
+		}
+	}
+}
+
 func TestPersistentKey(t *testing.T) {
 	expected := PersistentKey()
 	got := PersistentKey()
@@ -36,7 +49,7 @@ func TestPersistentKey(t *testing.T) {
 }
 
 func TestSet2Challenge12Decrypt(t *testing.T) {
-	expected := []byte("Rollin' in my 5.0\nWith my rag-top down so my hair can blow\nThe girlies on standby waving just to say hi\nDid you stop? No, I just drove by\n\x04\x04\x04\x04\x04\x04")
+	expected := []byte("Rollin' in my 5.0\nWith my rag-top down so my hair can blow\nThe girlies on standby waving just to say hi\nDid you stop? No, I just drove by\n")
 	got := Set2Challenge12Decrypt()
 
 	if !bytes.Equal(got, expected) {
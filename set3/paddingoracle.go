@@ -0,0 +1,112 @@
+package set3
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/mipearson/matasano"
+)
+
+var ErrNoValidPadding = errors.New("set3: padding oracle accepted no guess for a byte")
+
+// A PaddingOracle reports whether ciphertext, decrypted with CBC under iv,
+// ends in valid padding.
+type PaddingOracle func(iv, ciphertext []byte) bool
+
+// NewAESCBCPaddingOracle is the challenge 17 server: it decrypts with
+// AES-CBC under key and validates PKCS#7 padding strictly.
+func NewAESCBCPaddingOracle(key []byte) PaddingOracle {
+	return func(iv, ciphertext []byte) bool {
+		plaintext, err := matasano.TryDecryptAESCBC(ciphertext, key, iv)
+		if err != nil {
+			return false
+		}
+		_, err = matasano.Pkcs7Unpad(plaintext, len(iv))
+		return err == nil
+	}
+}
+
+// PaddingOracleDecrypt recovers the unpadded plaintext of ciphertext one
+// block at a time, asking oracle only whether crafted IVs give valid
+// padding.
+func PaddingOracleDecrypt(oracle PaddingOracle, iv, ciphertext []byte) ([]byte, error) {
+	bs := len(iv)
+	if bs == 0 || len(ciphertext) == 0 || len(ciphertext)%bs != 0 {
+		return nil, matasano.ErrNotBlockMultiple
+	}
+
+	plaintext := make([]byte, 0, len(ciphertext))
+	prev := iv
+	for i := 0; i < len(ciphertext); i += bs {
+		block := ciphertext[i : i+bs]
+		intermediate, err := decryptBlock(oracle, block)
+		if err != nil {
+			return nil, err
+		}
+		plaintext = append(plaintext, matasano.Xor(intermediate, prev)...)
+		prev = block
+	}
+	return matasano.Pkcs7Unpad(plaintext, bs)
+}
+
+// decryptBlock finds the raw block decryption of block, before it is XORed
+// with the previous ciphertext, by working from the last byte back: with
+// the bytes after position p already forcing padding value bs-p, the guess
+// for position p that the oracle accepts reveals that byte.
+func decryptBlock(oracle PaddingOracle, block []byte) ([]byte, error) {
+	bs := len(block)
+	intermediate := make([]byte, bs)
+	iv := make([]byte, bs)
+
+	for p := bs - 1; p >= 0; p-- {
+		pad := byte(bs - p)
+		for j := p + 1; j < bs; j++ {
+			iv[j] = intermediate[j] ^ pad
+		}
+
+		found := false
+		for guess := 0; guess < 256 && !found; guess++ {
+			iv[p] = byte(guess)
+			if !oracle(iv, block) {
+				continue
+			}
+			// On the last byte, valid padding may be \x02\x02 (or longer)
+			// rather than \x01. Changing the byte before it breaks any
+			// longer padding but leaves \x01 valid.
+			if p == bs-1 && p > 0 {
+				iv[p-1] ^= 0xff
+				ok := oracle(iv, block)
+				iv[p-1] ^= 0xff
+				if !ok {
+					continue
+				}
+			}
+			intermediate[p] = byte(guess) ^ pad
+			found = true
+		}
+		if !found {
+			return nil, ErrNoValidPadding
+		}
+	}
+	return intermediate, nil
+}
+
+// PaddingOracleEncrypt produces an IV and ciphertext that the oracle's
+// owner will decrypt to plaintext, without knowing the key (CBC-R). It
+// starts from a random final block and uses decryptBlock to choose each
+// previous block so that it decrypts to the plaintext wanted there.
+func PaddingOracleEncrypt(oracle PaddingOracle, plaintext []byte, blocksize int) (iv []byte, ciphertext []byte, err error) {
+	padded := matasano.Pkcs7Padding(plaintext, blocksize)
+	n := len(padded) / blocksize
+
+	blocks := make([][]byte, n+1)
+	blocks[n] = matasano.RandBytes(blocksize)
+	for i := n; i > 0; i-- {
+		intermediate, err := decryptBlock(oracle, blocks[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		blocks[i-1] = matasano.Xor(intermediate, padded[(i-1)*blocksize:i*blocksize])
+	}
+	return blocks[0], bytes.Join(blocks[1:], []byte{}), nil
+}
@@ -0,0 +1,72 @@
+package set3
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mipearson/matasano"
+)
+
+func TestPaddingOracleDecrypt(t *testing.T) {
+	key := matasano.RandBytes(16)
+	oracle := NewAESCBCPaddingOracle(key)
+
+	for _, plaintext := range []string{
+		"",
+		"YELLOW SUBMARINE",
+		"000000Now that the party is jumping",
+		"000009ith my rag-top down so my hair can blow",
+	} {
+		iv := matasano.RandBytes(16)
+		cipher := matasano.EncryptAESCBC(matasano.Pkcs7Padding([]byte(plaintext), 16), key, iv)
+		got, err := PaddingOracleDecrypt(oracle, iv, cipher)
+		if err != nil || !bytes.Equal(got, []byte(plaintext)) {
+			t.Errorf("TestPaddingOracleDecrypt(%q): got %q, %v", plaintext, got, err)
+		}
+	}
+}
+
+// decryptBlock starts from a zero IV, so a block whose raw decryption has
+// \x02 as its second-last byte is valid for two guesses at the last byte:
+// one giving \x01 and one giving \x02\x02. The attack has to pick \x01.
+// The block is also chosen so that the \x02\x02 guess is tried first.
+func TestPaddingOracleDecryptTwoTwo(t *testing.T) {
+	key := matasano.RandBytes(16)
+	oracle := NewAESCBCPaddingOracle(key)
+
+	var block []byte
+	for {
+		block = matasano.RandBytes(16)
+		raw := matasano.DecryptAESECB(block, key)
+		if raw[14] == 0x02 && raw[15]&0x02 != 0 {
+			break
+		}
+	}
+
+	got, err := decryptBlock(oracle, block)
+	expected := matasano.DecryptAESECB(block, key)
+	if err != nil || !bytes.Equal(got, expected) {
+		t.Errorf("TestPaddingOracleDecryptTwoTwo: got %x, %v expected %x", got, err, expected)
+	}
+}
+
+func TestPaddingOracleEncrypt(t *testing.T) {
+	key := matasano.RandBytes(16)
+	plaintext := []byte("comment1=cooking MCs;admin=true;comment2= like a pound of bacon")
+
+	iv, cipher, err := PaddingOracleEncrypt(NewAESCBCPaddingOracle(key), plaintext, 16)
+	if err != nil {
+		t.Fatalf("TestPaddingOracleEncrypt: %v", err)
+	}
+	got, err := matasano.Pkcs7Unpad(matasano.DecryptAESCBC(cipher, key, iv), 16)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("TestPaddingOracleEncrypt: got %q, %v expected %q", got, err, plaintext)
+	}
+}
+
+func TestPaddingOracleDecryptRejectsBadLength(t *testing.T) {
+	oracle := NewAESCBCPaddingOracle(matasano.RandBytes(16))
+	if _, err := PaddingOracleDecrypt(oracle, make([]byte, 16), make([]byte, 20)); err != matasano.ErrNotBlockMultiple {
+		t.Errorf("TestPaddingOracleDecryptRejectsBadLength: got %v expected %v", err, matasano.ErrNotBlockMultiple)
+	}
+}
@@ -0,0 +1,49 @@
+package set3
+
+import (
+	"github.com/mipearson/matasano/set1"
+)
+
+// BreakFixedNonceCTR recovers the keystream shared by ciphertexts that were
+// all encrypted under the same CTR key and nonce. Each keystream byte is
+// solved as a single-byte XOR over the column of ciphertext bytes at that
+// position.
+//
+// confidence[i] is between 0 and 1. It is the winning key's score margin over
+// the runner-up, scaled by the fraction of ciphertexts long enough to reach
+// position i, so the tail of the keystream, where only a few ciphertexts
+// contribute, reports low confidence.
+func BreakFixedNonceCTR(ciphertexts [][]byte) (keystream []byte, confidence []float64) {
+	maxLen := 0
+	for _, c := range ciphertexts {
+		if len(c) > maxLen {
+			maxLen = len(c)
+		}
+	}
+
+	keystream = make([]byte, maxLen)
+	confidence = make([]float64, maxLen)
+	for i := 0; i < maxLen; i++ {
+		column := make([]byte, 0, len(ciphertexts))
+		for _, c := range ciphertexts {
+			if i < len(c) {
+				column = append(column, c[i])
+			}
+		}
+
+		candidates := set1.DecodeSimpleXorCipher(column)
+		if len(candidates) == 0 {
+			continue
+		}
+		top := candidates.Top(2)
+		keystream[i] = top[0].Key()[0]
+
+		best := top[0].Score()
+		margin := 1.0
+		if len(top) > 1 {
+			margin = (best - top[1].Score()) / best
+		}
+		confidence[i] = margin * float64(len(column)) / float64(len(ciphertexts))
+	}
+	return keystream, confidence
+}
@@ -0,0 +1,61 @@
+package set3
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mipearson/matasano"
+)
+
+func lyricLines() [][]byte {
+	cipher, err := matasano.LoadBase64File(matasano.Data, "data/set1_challenge7.txt")
+	matasano.CheckErr(err)
+	plaintext := matasano.DecryptAESECB(cipher, []byte("YELLOW SUBMARINE"))
+
+	lines := [][]byte{}
+	for _, line := range bytes.Split(plaintext, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && line[0] != 4 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestBreakFixedNonceCTR(t *testing.T) {
+	key := matasano.RandBytes(16)
+	nonce := make([]byte, 8)
+	plaintexts := lyricLines()
+	ciphertexts := make([][]byte, len(plaintexts))
+	for i, p := range plaintexts {
+		ciphertexts[i] = matasano.EncryptAESCTR(p, key, nonce, matasano.CTRLittleEndian64)
+	}
+
+	keystream, confidence := BreakFixedNonceCTR(ciphertexts)
+
+	expected := matasano.EncryptAESCTR(make([]byte, len(keystream)), key, nonce, matasano.CTRLittleEndian64)
+
+	minLen := len(ciphertexts[0])
+	for _, c := range ciphertexts {
+		if len(c) < minLen {
+			minLen = len(c)
+		}
+	}
+
+	correct := 0
+	for i := range keystream {
+		if keystream[i] == expected[i] {
+			if i < minLen {
+				correct++
+			}
+		} else if confidence[i] > 0.05 {
+			t.Errorf("TestBreakFixedNonceCTR: keystream[%d] got %#02x expected %#02x with confidence %.2f", i, keystream[i], expected[i], confidence[i])
+		}
+	}
+	if correct < minLen*3/4 {
+		t.Errorf("TestBreakFixedNonceCTR: only %d of the first %d keystream bytes are correct", correct, minLen)
+	}
+	if last := confidence[len(confidence)-1]; last > 0.05 {
+		t.Errorf("TestBreakFixedNonceCTR: expected low confidence for the last byte, got %.2f", last)
+	}
+}
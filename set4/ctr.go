@@ -0,0 +1,129 @@
+package set4
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/mipearson/matasano"
+	"github.com/mipearson/matasano/set2"
+)
+
+var ErrShortKeystream = errors.New("set4: edit returned less keystream than ciphertext")
+
+// Challenge25Plaintext is the challenge 25 plaintext: the challenge 7
+// file, decrypted.
+func Challenge25Plaintext() ([]byte, error) {
+	cipher, err := matasano.LoadBase64File(matasano.Data, "data/set1_challenge7.txt")
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := matasano.TryDecryptAESECB(cipher, []byte("YELLOW SUBMARINE"))
+	if err != nil {
+		return nil, err
+	}
+	return matasano.Pkcs7Unpad(plaintext, 16)
+}
+
+// CTREditTarget is the challenge 25 target: it holds a CTR ciphertext and
+// exposes an edit function that re-encrypts part of it with new text.
+type CTREditTarget struct {
+	key        []byte
+	nonce      []byte
+	ciphertext []byte
+}
+
+func NewCTREditTarget(plaintext []byte) *CTREditTarget {
+	t := &CTREditTarget{key: matasano.RandBytes(16), nonce: matasano.RandBytes(8)}
+	t.ciphertext = matasano.EncryptAESCTR(plaintext, t.key, t.nonce, matasano.CTRLittleEndian64)
+	return t
+}
+
+func (t *CTREditTarget) Ciphertext() []byte {
+	return append([]byte{}, t.ciphertext...)
+}
+
+func (t *CTREditTarget) Edit(ciphertext []byte, offset int, newtext []byte) ([]byte, error) {
+	return matasano.TryEditAESCTR(append([]byte{}, ciphertext...), t.key, t.nonce, matasano.CTRLittleEndian64, offset, newtext)
+}
+
+// EditAt exposes Edit on the target's own ciphertext as an Encrypter, so
+// it can be attacked like any other oracle.
+func (t *CTREditTarget) EditAt(offset int) set2.Encrypter {
+	return func(newtext []byte) []byte {
+		edited, err := t.Edit(t.ciphertext, offset, newtext)
+		matasano.CheckErr(err)
+		return edited
+	}
+}
+
+// RecoverCTREditPlaintext recovers the plaintext behind ciphertext from an
+// edit oracle starting at offset 0: editing in zeros makes it return the
+// keystream itself.
+func RecoverCTREditPlaintext(ciphertext []byte, edit set2.Encrypter) ([]byte, error) {
+	keystream := edit(make([]byte, len(ciphertext)))
+	if len(keystream) < len(ciphertext) {
+		return nil, ErrShortKeystream
+	}
+	return matasano.Xor(ciphertext, keystream[:len(ciphertext)]), nil
+}
+
+// CTRCookie is the challenge 26 target: challenge 16's cookie, encrypted
+// with CTR instead of CBC.
+type CTRCookie struct {
+	key   []byte
+	nonce []byte
+}
+
+func NewCTRCookie() *CTRCookie {
+	return &CTRCookie{key: matasano.RandBytes(16), nonce: matasano.RandBytes(8)}
+}
+
+func (c *CTRCookie) Encrypt(userdata []byte) []byte {
+	return matasano.EncryptAESCTR(set2.Challenge16Plaintext(userdata), c.key, c.nonce, matasano.CTRLittleEndian64)
+}
+
+func (c *CTRCookie) IsAdmin(ciphertext []byte) (bool, error) {
+	plaintext, err := matasano.TryDecryptAESCTR(ciphertext, c.key, c.nonce, matasano.CTRLittleEndian64)
+	if err != nil {
+		return false, err
+	}
+	return set2.HasAdminField(plaintext), nil
+}
+
+// CTRBitflip returns a copy of ciphertext that decrypts with want in place
+// of known at offset. Unlike CBC, nothing else in the plaintext changes.
+func CTRBitflip(ciphertext []byte, offset int, known []byte, want []byte) ([]byte, error) {
+	if len(known) != len(want) {
+		return nil, matasano.ErrLengthMismatch
+	}
+	if offset < 0 || offset+len(known) > len(ciphertext) {
+		return nil, matasano.ErrInvalidOffset
+	}
+	flipped := append([]byte{}, ciphertext...)
+	for i := range known {
+		flipped[offset+i] ^= known[i] ^ want[i]
+	}
+	return flipped, nil
+}
+
+// FindInputOffset finds where a stream cipher oracle puts the attacker's
+// input: the first byte that changes when the input does.
+func FindInputOffset(e set2.Encrypter) int {
+	a, b := e([]byte("A")), e([]byte("B"))
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return -1
+}
+
+func Set4Challenge26ForceAdmin(e set2.Encrypter) ([]byte, error) {
+	want := []byte(";admin=true;")
+	known := bytes.Repeat([]byte("A"), len(want))
+	offset := FindInputOffset(e)
+	if offset < 0 {
+		return nil, matasano.ErrInvalidOffset
+	}
+	return CTRBitflip(e(known), offset, known, want)
+}
@@ -0,0 +1,92 @@
+package set4
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mipearson/matasano"
+	"github.com/mipearson/matasano/set2"
+)
+
+func TestSet4Challenge25(t *testing.T) {
+	plaintext, err := Challenge25Plaintext()
+	if err != nil {
+		t.Fatalf("TestSet4Challenge25: %v", err)
+	}
+	if !bytes.HasPrefix(plaintext, []byte("I'm back and I'm ringin' the bell")) {
+		t.Fatalf("TestSet4Challenge25: unexpected plaintext %q", plaintext[:40])
+	}
+
+	target := NewCTREditTarget(plaintext)
+	got, err := RecoverCTREditPlaintext(target.Ciphertext(), target.EditAt(0))
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("TestSet4Challenge25: got %q, %v", got, err)
+	}
+}
+
+func TestSet4Challenge25OverHTTP(t *testing.T) {
+	target := NewCTREditTarget([]byte("Rollin' in my 5.0"))
+	server := httptest.NewServer(set2.OracleHandler(set2.EncrypterOracle(target.EditAt(0))))
+	defer server.Close()
+
+	var got []byte
+	err := set2.RunWithOracle(context.Background(), set2.HTTPOracle{URL: server.URL}, func(e set2.Encrypter) {
+		var err error
+		got, err = RecoverCTREditPlaintext(target.Ciphertext(), e)
+		matasano.CheckErr(err)
+	})
+	if err != nil || string(got) != "Rollin' in my 5.0" {
+		t.Errorf("TestSet4Challenge25OverHTTP: got %q, %v", got, err)
+	}
+}
+
+func TestCTREditTargetEdit(t *testing.T) {
+	target := NewCTREditTarget([]byte("YELLOW SUBMARINE"))
+	original := target.Ciphertext()
+	edited, err := target.Edit(original, 7, []byte("sub"))
+	if err != nil {
+		t.Fatalf("TestCTREditTargetEdit: %v", err)
+	}
+	if !bytes.Equal(original, target.Ciphertext()) {
+		t.Errorf("TestCTREditTargetEdit: Edit modified its input")
+	}
+	if !bytes.Equal(edited[:7], original[:7]) || !bytes.Equal(edited[10:], original[10:]) || bytes.Equal(edited[7:10], original[7:10]) {
+		t.Errorf("TestCTREditTargetEdit: expected only bytes 7-9 to change, got %x from %x", edited, original)
+	}
+}
+
+func TestCTRCookieQuotesUserdata(t *testing.T) {
+	c := NewCTRCookie()
+	admin, err := c.IsAdmin(c.Encrypt([]byte(";admin=true;")))
+	if err != nil || admin {
+		t.Errorf("TestCTRCookieQuotesUserdata: got %v, %v expected false", admin, err)
+	}
+}
+
+func TestSet4Challenge26ForceAdmin(t *testing.T) {
+	c := NewCTRCookie()
+	if got := FindInputOffset(c.Encrypt); got != len("comment1=cooking%20MCs;userdata=") {
+		t.Errorf("TestSet4Challenge26ForceAdmin: FindInputOffset got %d", got)
+	}
+
+	forged, err := Set4Challenge26ForceAdmin(c.Encrypt)
+	if err != nil {
+		t.Fatalf("TestSet4Challenge26ForceAdmin: %v", err)
+	}
+	admin, err := c.IsAdmin(forged)
+	if err != nil || !admin {
+		t.Errorf("TestSet4Challenge26ForceAdmin: got %v, %v expected true", admin, err)
+	}
+}
+
+func TestCTRBitflipErrors(t *testing.T) {
+	if _, err := CTRBitflip(make([]byte, 4), 2, []byte("abc"), []byte("xyz")); !errors.Is(err, matasano.ErrInvalidOffset) {
+		t.Errorf("TestCTRBitflipErrors: got %v expected %v", err, matasano.ErrInvalidOffset)
+	}
+	if _, err := CTRBitflip(make([]byte, 4), 0, []byte("ab"), []byte("x")); !errors.Is(err, matasano.ErrLengthMismatch) {
+		t.Errorf("TestCTRBitflipErrors: got %v expected %v", err, matasano.ErrLengthMismatch)
+	}
+}
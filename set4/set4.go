@@ -0,0 +1,78 @@
+package set4
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/mipearson/matasano"
+)
+
+var (
+	ErrShortCiphertext = errors.New("set4: need at least three blocks of ciphertext")
+	ErrNoLeak          = errors.New("set4: target did not leak its plaintext")
+)
+
+// HighASCIIError is what KeyAsIV.Decrypt returns for plaintext containing
+// bytes above 0x7f. Like the challenge's target, it leaks the plaintext.
+type HighASCIIError struct {
+	Plaintext []byte
+}
+
+func (e *HighASCIIError) Error() string {
+	return fmt.Sprintf("set4: plaintext contains high-ASCII bytes: %q", e.Plaintext)
+}
+
+// KeyAsIV is the challenge 27 target: AES-CBC with the key reused as the
+// IV.
+type KeyAsIV struct {
+	key []byte
+}
+
+func NewKeyAsIV() *KeyAsIV {
+	return &KeyAsIV{key: matasano.RandBytes(16)}
+}
+
+func (k *KeyAsIV) Encrypt(plaintext []byte) []byte {
+	return matasano.EncryptAESCBC(matasano.Pkcs7Padding(plaintext, 16), k.key, k.key)
+}
+
+// Decrypt checks the plaintext for high-ASCII bytes before its padding, so
+// a forged ciphertext always gets its plaintext reported back.
+func (k *KeyAsIV) Decrypt(ciphertext []byte) error {
+	plaintext, err := matasano.TryDecryptAESCBC(ciphertext, k.key, k.key)
+	if err != nil {
+		return err
+	}
+	for _, b := range plaintext {
+		if b > 0x7f {
+			return &HighASCIIError{plaintext}
+		}
+	}
+	_, err = matasano.Pkcs7Unpad(plaintext, 16)
+	return err
+}
+
+// RecoverKeyAsIV recovers the key of a target that uses it as the IV. It
+// sends C1, 0, C1 in place of the first three blocks; then P'1 = D(C1) ^ key
+// and P'3 = D(C1) ^ 0, so P'1 ^ P'3 is the key.
+func RecoverKeyAsIV(ciphertext []byte, decrypt func([]byte) error) ([]byte, error) {
+	bs := 16
+	if len(ciphertext) < 3*bs {
+		return nil, ErrShortCiphertext
+	}
+
+	c1 := ciphertext[:bs]
+	forged := bytes.Join([][]byte{c1, make([]byte, bs), c1, ciphertext[3*bs:]}, []byte{})
+
+	var leak *HighASCIIError
+	if !errors.As(decrypt(forged), &leak) {
+		return nil, ErrNoLeak
+	}
+	return matasano.Xor(leak.Plaintext[:bs], leak.Plaintext[2*bs:3*bs]), nil
+}
+
+func Set4Challenge27RecoverKey(target *KeyAsIV) ([]byte, error) {
+	ciphertext := target.Encrypt(bytes.Repeat([]byte("A"), 48))
+	return RecoverKeyAsIV(ciphertext, target.Decrypt)
+}
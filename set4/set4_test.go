@@ -0,0 +1,48 @@
+package set4
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/mipearson/matasano"
+)
+
+func TestKeyAsIVDecrypt(t *testing.T) {
+	target := NewKeyAsIV()
+	if err := target.Decrypt(target.Encrypt([]byte("comment1=cooking MCs"))); err != nil {
+		t.Errorf("TestKeyAsIVDecrypt: got %v expected nil", err)
+	}
+
+	var leak *HighASCIIError
+	err := target.Decrypt(target.Encrypt([]byte("caf\xe9")))
+	if !errors.As(err, &leak) || !bytes.HasPrefix(leak.Plaintext, []byte("caf\xe9")) {
+		t.Errorf("TestKeyAsIVDecrypt: got %v expected a HighASCIIError", err)
+	}
+}
+
+func TestSet4Challenge27RecoverKey(t *testing.T) {
+	target := NewKeyAsIV()
+	got, err := Set4Challenge27RecoverKey(target)
+	if err != nil || !bytes.Equal(got, target.key) {
+		t.Errorf("TestSet4Challenge27RecoverKey: got %x, %v expected %x", got, err, target.key)
+	}
+
+	// The recovered key decrypts anything else the target produced.
+	secret := []byte("attack at dawn, bring snacks")
+	plaintext := matasano.DecryptAESCBC(target.Encrypt(secret), got, got)
+	if !bytes.HasPrefix(plaintext, secret) {
+		t.Errorf("TestSet4Challenge27RecoverKey: decrypting with the recovered key got %q", plaintext)
+	}
+}
+
+func TestRecoverKeyAsIVErrors(t *testing.T) {
+	target := NewKeyAsIV()
+	if _, err := RecoverKeyAsIV(target.Encrypt([]byte("short")), target.Decrypt); err != ErrShortCiphertext {
+		t.Errorf("TestRecoverKeyAsIVErrors: got %v expected %v", err, ErrShortCiphertext)
+	}
+	silent := func([]byte) error { return nil }
+	if _, err := RecoverKeyAsIV(make([]byte, 48), silent); err != ErrNoLeak {
+		t.Errorf("TestRecoverKeyAsIVErrors: got %v expected %v", err, ErrNoLeak)
+	}
+}
@@ -0,0 +1,247 @@
+package matasano
+
+import (
+	"crypto/cipher"
+	"io"
+)
+
+// streamChunk bounds how much ciphertext the streaming block readers and
+// writers hold at once, keeping memory use independent of the input size.
+const streamChunk = 4096
+
+type blockWriter struct {
+	w       io.Writer
+	mode    cipher.BlockMode
+	padding Padding
+	partial []byte
+	scratch []byte
+	closed  bool
+	err     error
+}
+
+// NewBlockWriter encrypts everything written to it with mode and writes the
+// ciphertext to w as whole blocks become available. Close pads the final
+// block and closes w if it is an io.Closer.
+func NewBlockWriter(w io.Writer, mode cipher.BlockMode, padding Padding) io.WriteCloser {
+	bs := mode.BlockSize()
+	return &blockWriter{
+		w:       w,
+		mode:    mode,
+		padding: padding,
+		partial: make([]byte, 0, bs),
+		scratch: make([]byte, streamChunk-streamChunk%bs),
+	}
+}
+
+// Write returns the number of bytes of p that were either written to w or
+// buffered for the next block. Once a write to w fails the writer's mode
+// state can no longer be trusted, so that error is returned from every
+// later call.
+func (b *blockWriter) Write(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+	bs := b.mode.BlockSize()
+	n := 0
+
+	if len(b.partial) > 0 {
+		take := bs - len(b.partial)
+		if take > len(p) {
+			take = len(p)
+		}
+		b.partial = append(b.partial, p[:take]...)
+		if len(b.partial) < bs {
+			return take, nil
+		}
+		n += take
+		if err := b.flush(b.partial); err != nil {
+			return n, err
+		}
+		b.partial = b.partial[:0]
+	}
+
+	for len(p)-n >= bs {
+		size := len(p) - n - (len(p)-n)%bs
+		if size > len(b.scratch) {
+			size = len(b.scratch)
+		}
+		if err := b.flush(p[n : n+size]); err != nil {
+			return n, err
+		}
+		n += size
+	}
+	b.partial = append(b.partial, p[n:]...)
+	return len(p), nil
+}
+
+func (b *blockWriter) flush(blocks []byte) error {
+	dst := b.scratch[:len(blocks)]
+	b.mode.CryptBlocks(dst, blocks)
+	if _, err := b.w.Write(dst); err != nil {
+		b.err = err
+		return err
+	}
+	return nil
+}
+
+func (b *blockWriter) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	if b.err != nil {
+		return b.err
+	}
+
+	last := b.padding.Pad(b.partial, b.mode.BlockSize())
+	if err := checkBlocks(last, b.mode.BlockSize()); err != nil {
+		return err
+	}
+	if len(last) > 0 {
+		if err := b.flush(last); err != nil {
+			return err
+		}
+	}
+	if c, ok := b.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+type blockReader struct {
+	r       io.Reader
+	mode    cipher.BlockMode
+	padding Padding
+	pending []byte
+	held    []byte
+	out     []byte
+	scratch []byte
+	err     error
+}
+
+// NewBlockReader decrypts the ciphertext read from r with mode. The last
+// block is held back until r returns io.EOF so that its padding can be
+// checked and removed; a padding failure is returned in place of io.EOF.
+func NewBlockReader(r io.Reader, mode cipher.BlockMode, padding Padding) io.Reader {
+	return &blockReader{
+		r:       r,
+		mode:    mode,
+		padding: padding,
+		scratch: make([]byte, streamChunk),
+	}
+}
+
+func (b *blockReader) Read(p []byte) (int, error) {
+	for len(b.out) == 0 && b.err == nil {
+		b.fill()
+	}
+	if len(b.out) > 0 {
+		n := copy(p, b.out)
+		b.out = b.out[n:]
+		return n, nil
+	}
+	return 0, b.err
+}
+
+func (b *blockReader) fill() {
+	bs := b.mode.BlockSize()
+	n, err := b.r.Read(b.scratch)
+	b.pending = append(b.pending, b.scratch[:n]...)
+
+	if full := len(b.pending) - len(b.pending)%bs; full > 0 {
+		plain := append(b.held, make([]byte, full)...)
+		b.mode.CryptBlocks(plain[len(b.held):], b.pending[:full])
+		b.pending = append(b.pending[:0], b.pending[full:]...)
+
+		cut := len(plain) - bs
+		b.out = plain[:cut]
+		b.held = append([]byte{}, plain[cut:]...)
+	}
+
+	switch {
+	case err == io.EOF:
+		if len(b.pending) != 0 {
+			b.err = ErrNotBlockMultiple
+			return
+		}
+		last, perr := b.padding.Unpad(b.held, bs)
+		if perr != nil {
+			b.err = perr
+			return
+		}
+		b.out = append(b.out, last...)
+		b.held = nil
+		b.err = io.EOF
+	case err != nil:
+		b.err = err
+	}
+}
+
+func NewAESECBWriter(w io.Writer, key []byte, padding Padding) (io.WriteCloser, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewBlockWriter(w, NewECBEncrypter(aes), padding), nil
+}
+
+func NewAESECBReader(r io.Reader, key []byte, padding Padding) (io.Reader, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewBlockReader(r, NewECBDecrypter(aes), padding), nil
+}
+
+func NewAESCBCWriter(w io.Writer, key []byte, iv []byte, padding Padding) (io.WriteCloser, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := NewCBCEncrypter(aes, iv)
+	if err != nil {
+		return nil, err
+	}
+	return NewBlockWriter(w, mode, padding), nil
+}
+
+func NewAESCBCReader(r io.Reader, key []byte, iv []byte, padding Padding) (io.Reader, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := NewCBCDecrypter(aes, iv)
+	if err != nil {
+		return nil, err
+	}
+	return NewBlockReader(r, mode, padding), nil
+}
+
+func newAESCTR(key []byte, nonce []byte, layout CTRLayout) (cipher.Stream, error) {
+	aes, err := newAESCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewCTRWithLayout(aes, nonce, layout)
+}
+
+// CTR needs no padding, so the AES-CTR writer and reader are crypto/cipher's
+// StreamWriter and StreamReader over our keystream.
+func NewAESCTRWriter(w io.Writer, key []byte, nonce []byte, layout CTRLayout) (io.WriteCloser, error) {
+	stream, err := newAESCTR(key, nonce, layout)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamWriter{S: stream, W: w}, nil
+}
+
+func NewAESCTRReader(r io.Reader, key []byte, nonce []byte, layout CTRLayout) (io.Reader, error) {
+	stream, err := newAESCTR(key, nonce, layout)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamReader{S: stream, R: r}, nil
+}
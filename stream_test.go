@@ -0,0 +1,140 @@
+package matasano
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"testing/iotest"
+)
+
+func TestAESStreams(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	iv := RandBytes(16)
+	nonce := RandBytes(8)
+	src := RandBytes(3*streamChunk + 7)
+
+	cbc := EncryptAESCBCPadded(src, key, iv, Pkcs7)
+	ecb := EncryptAESECBPadded(src, key, Iso7816)
+	ctr := EncryptAESCTR(src, key, nonce, CTRLittleEndian64)
+
+	cases := []struct {
+		name     string
+		writer   func(io.Writer) (io.WriteCloser, error)
+		reader   func(io.Reader) (io.Reader, error)
+		expected []byte
+	}{
+		{
+			"CBC",
+			func(w io.Writer) (io.WriteCloser, error) { return NewAESCBCWriter(w, key, iv, Pkcs7) },
+			func(r io.Reader) (io.Reader, error) { return NewAESCBCReader(r, key, iv, Pkcs7) },
+			cbc,
+		},
+		{
+			"ECB",
+			func(w io.Writer) (io.WriteCloser, error) { return NewAESECBWriter(w, key, Iso7816) },
+			func(r io.Reader) (io.Reader, error) { return NewAESECBReader(r, key, Iso7816) },
+			ecb,
+		},
+		{
+			"CTR",
+			func(w io.Writer) (io.WriteCloser, error) { return NewAESCTRWriter(w, key, nonce, CTRLittleEndian64) },
+			func(r io.Reader) (io.Reader, error) { return NewAESCTRReader(r, key, nonce, CTRLittleEndian64) },
+			ctr,
+		},
+	}
+
+	for _, c := range cases {
+		var buf bytes.Buffer
+		w, err := c.writer(&buf)
+		CheckErr(err)
+		// Write in pieces that straddle block boundaries.
+		for i := 0; i < len(src); i += 1000 {
+			end := i + 1000
+			if end > len(src) {
+				end = len(src)
+			}
+			_, err := w.Write(src[i:end])
+			CheckErr(err)
+		}
+		CheckErr(w.Close())
+		if !bytes.Equal(buf.Bytes(), c.expected) {
+			t.Errorf("TestAESStreams(%s): streamed ciphertext differs from the whole-slice encryption", c.name)
+		}
+
+		r, err := c.reader(iotest.HalfReader(bytes.NewReader(c.expected)))
+		CheckErr(err)
+		got, err := ioutil.ReadAll(r)
+		if err != nil || !bytes.Equal(got, src) {
+			t.Errorf("TestAESStreams(%s): streamed decryption got %d bytes, %v expected %d bytes", c.name, len(got), err, len(src))
+		}
+	}
+}
+
+type failingWriter struct {
+	limit int
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > f.limit {
+		return 0, errWriteFailed
+	}
+	f.limit -= len(p)
+	return len(p), nil
+}
+
+func TestBlockWriterShortWrite(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	w, err := NewAESECBWriter(&failingWriter{streamChunk + 16}, key, Pkcs7)
+	CheckErr(err)
+
+	n, err := w.Write(make([]byte, 10))
+	if n != 10 || err != nil {
+		t.Errorf("Write buffering a partial block: got %d, %v expected 10, nil", n, err)
+	}
+	// The first 6 bytes complete the buffered block and the next chunk is
+	// written, then the chunk after that fails.
+	n, err = w.Write(make([]byte, 3*streamChunk))
+	if n != 6+streamChunk || err != errWriteFailed {
+		t.Errorf("Write with a failing destination: got %d, %v expected %d, %v", n, err, 6+streamChunk, errWriteFailed)
+	}
+	if n, err := w.Write([]byte("x")); n != 0 || err != errWriteFailed {
+		t.Errorf("Write after a failure: got %d, %v expected 0, %v", n, err, errWriteFailed)
+	}
+	if err := w.Close(); err != errWriteFailed {
+		t.Errorf("Close after a failure: got %v expected %v", err, errWriteFailed)
+	}
+}
+
+func TestBlockWriterFailedFlush(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+	w, err := NewAESECBWriter(&failingWriter{0}, key, Pkcs7)
+	CheckErr(err)
+
+	_, err = w.Write(make([]byte, 10))
+	CheckErr(err)
+	// The first 6 bytes complete the buffered block, which fails to flush.
+	if n, err := w.Write(make([]byte, 20)); n != 6 || err != errWriteFailed {
+		t.Errorf("Write completing a block that fails to flush: got %d, %v expected 6, %v", n, err, errWriteFailed)
+	}
+}
+
+func TestBlockReaderErrors(t *testing.T) {
+	key := []byte("YELLOW SUBMARINE")
+
+	r, err := NewAESECBReader(bytes.NewReader(make([]byte, 20)), key, Pkcs7)
+	CheckErr(err)
+	if _, err := ioutil.ReadAll(r); err != ErrNotBlockMultiple {
+		t.Errorf("NewAESECBReader on 20 bytes: got %v, expected ErrNotBlockMultiple", err)
+	}
+
+	cipher := EncryptAESECB([]byte("YELLOW SUBMARINEYELLOW SUBMARINE"), key)
+	r, err = NewAESECBReader(bytes.NewReader(cipher), key, Pkcs7)
+	CheckErr(err)
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Errorf("NewAESECBReader on unpadded ciphertext: expected a padding error")
+	}
+}